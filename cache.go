@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type cachedProject struct {
+	Path string `json:"path"`
+	Kind string `json:"kind,omitempty"`
+}
+
+type cachedRoot struct {
+	Options  string           `json:"options"`
+	Dirs     map[string]int64 `json:"dirs"`
+	Projects []cachedProject  `json:"projects"`
+}
+
+// projectCache is the on-disk index of discovered projects, keyed by root.
+type projectCache struct {
+	Roots map[string]cachedRoot `json:"roots"`
+}
+
+func newProjectCache() projectCache {
+	return projectCache{Roots: map[string]cachedRoot{}}
+}
+
+func cacheDir() string {
+	return filepath.Join(expandPath("${XDG_CACHE_HOME}"), "mux-sesh")
+}
+
+func projectCachePath() string {
+	return filepath.Join(cacheDir(), "projects.json")
+}
+
+func loadProjectCache() projectCache {
+	data, err := os.ReadFile(projectCachePath())
+	if err != nil {
+		return newProjectCache()
+	}
+
+	var cache projectCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Roots == nil {
+		return newProjectCache()
+	}
+	return cache
+}
+
+func saveProjectCache(cache projectCache) error {
+	if err := os.MkdirAll(cacheDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(projectCachePath(), data)
+}
+
+// writeFileAtomic writes to a temp file and renames it over path, so another
+// instance reading path never sees a half-written file.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (o walkOptions) cacheKey() string {
+	return fmt.Sprintf("depth=%d ignore=%q symlinks=%t markers=%q", o.maxDepth, o.ignore, o.followSymlinks, o.markers)
+}
+
+// lookup returns the cached scan of root when it was made with the same
+// options. With verify set, the scan is only returned if none of the
+// directories it read have been modified since.
+func (c projectCache) lookup(root string, opts walkOptions, verify bool) (rootScan, bool) {
+	entry, ok := c.Roots[root]
+	if !ok || entry.Options != opts.cacheKey() {
+		return rootScan{}, false
+	}
+
+	if verify {
+		for dir, mtime := range entry.Dirs {
+			info, err := os.Stat(dir)
+			if err != nil || info.ModTime().UnixNano() != mtime {
+				return rootScan{}, false
+			}
+		}
+	}
+
+	scan := rootScan{dirs: entry.Dirs, complete: true}
+	for _, p := range entry.Projects {
+		scan.projects = append(scan.projects, project{path: p.Path, kind: p.Kind})
+	}
+	return scan, true
+}
+
+func (c projectCache) store(root string, opts walkOptions, scan rootScan) {
+	entry := cachedRoot{
+		Options: opts.cacheKey(),
+		Dirs:    scan.dirs,
+	}
+	for _, p := range scan.projects {
+		entry.Projects = append(entry.Projects, cachedProject{Path: p.path, Kind: p.kind})
+	}
+	c.Roots[root] = entry
+}
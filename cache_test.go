@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveProjectCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	cache := newProjectCache()
+	cache.Roots["/src"] = cachedRoot{
+		Options:  "depth=2",
+		Dirs:     map[string]int64{"/src": 1},
+		Projects: []cachedProject{{Path: "/src/app", Kind: "git"}},
+	}
+	if err := saveProjectCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadProjectCache(); !reflect.DeepEqual(got, cache) {
+		t.Errorf("loadProjectCache = %+v, want %+v", got, cache)
+	}
+
+	entries, err := os.ReadDir(cacheDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "projects.json" {
+		t.Errorf("cache dir holds %v, want only projects.json", entries)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const cliUsage = `Usage: mux-sesh [command] [args]
+
+Run without a command to start the interactive session manager.
+
+Commands:
+  switch <name>        Switch to an existing session
+  new <path|name>      Create a session for a directory, or a named session
+  kill <name>          Kill a session
+  rename <old> <new>   Rename a session
+  clone <url> [name] [options]
+                       Clone a repository and create a session for it,
+                       optionally into a different directory name. Options:
+                       --depth N (0 for a full clone), --branch <branch|tag>,
+                       --[no-]recurse-submodules, --filter <spec>,
+                       --sparse <dir,dir...>
+  save [name|--auto]   Save all sessions to a snapshot, named after the
+                       current time unless a name is given. --auto saves a
+                       rotating automatic snapshot if anything changed
+  restore [name]       Recreate the sessions of a snapshot (the newest by
+                       default) that aren't running
+  diff <old> [new]     Show what changed between two snapshots, or between
+                       a snapshot and the running sessions
+  daemon               Save automatic snapshots on the configured interval
+  check                Report problems with the configuration
+  help                 Show this help
+`
+
+type cliCommand struct {
+	minArgs int
+	maxArgs int // negative for no limit
+	run     func(t Tmux, args []string, config Config, stdout io.Writer) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"switch":  {minArgs: 1, maxArgs: 1, run: cliSwitch},
+	"new":     {minArgs: 1, maxArgs: 1, run: cliNew},
+	"kill":    {minArgs: 1, maxArgs: 1, run: cliKill},
+	"rename":  {minArgs: 2, maxArgs: 2, run: cliRename},
+	"clone":   {minArgs: 1, maxArgs: -1, run: cliClone},
+	"save":    {minArgs: 0, maxArgs: 1, run: cliSave},
+	"restore": {minArgs: 0, maxArgs: 1, run: cliRestore},
+	"diff":    {minArgs: 1, maxArgs: 2, run: cliDiff},
+	"daemon":  {minArgs: 0, maxArgs: 0, run: cliDaemon},
+	"check":   {minArgs: 0, maxArgs: 0, run: cliCheck},
+}
+
+func runCLI(args []string, config Config, t Tmux, stdout, stderr io.Writer) int {
+	name := args[0]
+	switch name {
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	}
+
+	command, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "mux-sesh: unknown command %q\n\n%s", name, cliUsage)
+		return 2
+	}
+
+	if n := len(args) - 1; n < command.minArgs || (command.maxArgs >= 0 && n > command.maxArgs) {
+		expected := fmt.Sprint(command.minArgs)
+		if command.maxArgs < 0 {
+			expected = fmt.Sprintf("at least %d", command.minArgs)
+		} else if command.maxArgs != command.minArgs {
+			expected = fmt.Sprintf("%d-%d", command.minArgs, command.maxArgs)
+		}
+		fmt.Fprintf(stderr, "mux-sesh: %s expects %s argument(s), got %d\n\n%s", name, expected, n, cliUsage)
+		return 2
+	}
+
+	if err := command.run(t, args[1:], config, stdout); err != nil {
+		fmt.Fprintf(stderr, "mux-sesh %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func cliSwitch(t Tmux, args []string, config Config, stdout io.Writer) error {
+	if !sessionExists(t, args[0]) {
+		return fmt.Errorf("session '%s' not found", args[0])
+	}
+	if err := switchTmuxSession(t, args[0]); err != nil {
+		return err
+	}
+	recordUse(sessionKey(args[0]))
+	return nil
+}
+
+func cliNew(t Tmux, args []string, config Config, stdout io.Writer) error {
+	target := args[0]
+	info, err := os.Stat(target)
+	if strings.ContainsAny(target, "/"+string(filepath.Separator)) {
+		// An argument with a path separator is a path, never a session name.
+		if err != nil {
+			return fmt.Errorf("no such directory: %s", target)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", target)
+		}
+	}
+	if err == nil && info.IsDir() {
+		path := absPath(target)
+		if err := createTmuxSession(t, config, path); err != nil {
+			return err
+		}
+		recordUse(projectKey(path), sessionKey(sessionNameForPath(path)))
+		return nil
+	}
+
+	if err := createNamedTmuxSession(t, config, target); err != nil {
+		return err
+	}
+	recordUse(sessionKey(sanitizeSessionName(target)))
+	return nil
+}
+
+func cliKill(t Tmux, args []string, config Config, stdout io.Writer) error {
+	if !sessionExists(t, args[0]) {
+		return fmt.Errorf("session '%s' not found", args[0])
+	}
+	return killTmuxSession(t, args[0])
+}
+
+func cliRename(t Tmux, args []string, config Config, stdout io.Writer) error {
+	if !sessionExists(t, args[0]) {
+		return fmt.Errorf("session '%s' not found", args[0])
+	}
+	if err := renameTmuxSession(t, args[0], args[1]); err != nil {
+		return err
+	}
+	renameInHistory(args[0], sanitizeSessionName(args[1]))
+	return nil
+}
+
+func cliClone(t Tmux, args []string, config Config, stdout io.Writer) error {
+	url, name, opts, err := parseCloneRequest(args)
+	if err != nil {
+		return err
+	}
+	if !isRepoURL(url, config) {
+		return fmt.Errorf("not a supported repository URL: %s", url)
+	}
+
+	clonedPath, err := cloneRepo(url, config, name, opts)
+	if err != nil {
+		var conflict *cloneConflictError
+		if errors.As(err, &conflict) {
+			return fmt.Errorf("%v; pass a different name: mux-sesh clone %s <name>", err, url)
+		}
+		return err
+	}
+	if err := createTmuxSession(t, config, clonedPath); err != nil {
+		return err
+	}
+	recordUse(projectKey(clonedPath), sessionKey(sessionNameForPath(clonedPath)))
+	return nil
+}
+
+func cliSave(t Tmux, args []string, config Config, stdout io.Writer) error {
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "--auto" {
+		path, err := saveAutoSnapshot(t, config)
+		if path != "" {
+			fmt.Fprintf(stdout, "Saved %s\n", path)
+		}
+		return err
+	}
+
+	snapshot, err := takeSnapshot(t)
+	if err != nil {
+		return err
+	}
+	path, err := saveSnapshot(snapshot, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Saved %d session(s) to %s\n", len(snapshot.Sessions), path)
+	return nil
+}
+
+func cliRestore(t Tmux, args []string, config Config, stdout io.Writer) error {
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	saved, err := findSnapshot(name)
+	if err != nil {
+		return err
+	}
+	restored, skipped, err := restoreSnapshot(t, config, saved.snapshot)
+	if len(restored) > 0 {
+		fmt.Fprintf(stdout, "Restored %s\n", strings.Join(restored, ", "))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(stdout, "Skipped running session(s) %s\n", strings.Join(skipped, ", "))
+	}
+	return err
+}
+
+func cliDiff(t Tmux, args []string, config Config, stdout io.Writer) error {
+	before, err := findSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	var after Snapshot
+	if len(args) > 1 {
+		saved, err := findSnapshot(args[1])
+		if err != nil {
+			return err
+		}
+		after = saved.snapshot
+	} else if after, err = takeSnapshot(t); err != nil {
+		return err
+	}
+
+	changes := diffSnapshots(before.snapshot, after)
+	if len(changes) == 0 {
+		fmt.Fprintln(stdout, "No changes")
+	}
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+	return nil
+}
+
+// cliDaemon saves an automatic snapshot right away and then on every
+// interval until it is interrupted. A lock file keeps a second daemon, say
+// one started again from tmux.conf, from running alongside it.
+func cliDaemon(t Tmux, args []string, config Config, stdout io.Writer) error {
+	interval, err := time.ParseDuration(config.AutoSnapshot.Interval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid auto snapshot interval %q", config.AutoSnapshot.Interval)
+	}
+
+	lock, err := lockSnapshotFile("daemon.lock", false)
+	if errors.Is(err, errLocked) {
+		return fmt.Errorf("another daemon is already running")
+	} else if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	fmt.Fprintf(stdout, "Saving snapshots every %s, keeping %d\n", interval, config.AutoSnapshot.Keep)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		path, err := saveAutoSnapshot(t, config)
+		if err != nil {
+			fmt.Fprintf(stdout, "Snapshot failed: %v\n", err)
+		} else if path != "" {
+			fmt.Fprintf(stdout, "Saved %s\n", path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func cliCheck(t Tmux, args []string, config Config, stdout io.Writer) error {
+	problems := config.Validate()
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", GetConfigPath(), strings.Join(problems, "; "))
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
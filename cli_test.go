@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	tests := []struct {
+		args     []string
+		code     int
+		stderr   string
+		sessions []string
+	}{
+		{[]string{"help"}, 0, "", []string{"alpha", "beta"}},
+		{[]string{"bogus"}, 2, `mux-sesh: unknown command "bogus"`, []string{"alpha", "beta"}},
+		{[]string{"switch"}, 2, "mux-sesh: switch expects 1 argument(s), got 0", []string{"alpha", "beta"}},
+		{[]string{"rename", "alpha", "b", "c"}, 2, "mux-sesh: rename expects 2 argument(s), got 3", []string{"alpha", "beta"}},
+		{[]string{"diff"}, 2, "mux-sesh: diff expects 1-2 argument(s), got 0", []string{"alpha", "beta"}},
+		{[]string{"clone"}, 2, "mux-sesh: clone expects at least 1 argument(s), got 0", []string{"alpha", "beta"}},
+		{[]string{"switch", "gamma"}, 1, "mux-sesh switch: session 'gamma' not found\n", []string{"alpha", "beta"}},
+		{[]string{"kill", "gamma"}, 1, "mux-sesh kill: session 'gamma' not found\n", []string{"alpha", "beta"}},
+		{[]string{"rename", "gamma", "delta"}, 1, "mux-sesh rename: session 'gamma' not found\n", []string{"alpha", "beta"}},
+		{[]string{"new", "./typo"}, 1, "mux-sesh new: no such directory: ./typo\n", []string{"alpha", "beta"}},
+		{[]string{"switch", "beta"}, 0, "", []string{"alpha", "beta"}},
+		{[]string{"kill", "alpha"}, 0, "", []string{"beta"}},
+		{[]string{"rename", "alpha", "work"}, 0, "", []string{"beta", "work"}},
+		{[]string{"new", "scratch"}, 0, "", []string{"alpha", "beta", "scratch"}},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_DATA_HOME", "")
+			t.Setenv("TMUX", "/tmp/tmux-test,1,0")
+			f := newFakeTmux("alpha", "beta")
+
+			var stdout, stderr bytes.Buffer
+			code := runCLI(test.args, DefaultConfig(), f, &stdout, &stderr)
+			if code != test.code {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, test.code, stderr.String())
+			}
+			if test.stderr == "" && stderr.Len() > 0 {
+				t.Errorf("stderr = %q, want nothing", stderr.String())
+			} else if !strings.HasPrefix(stderr.String(), test.stderr) {
+				t.Errorf("stderr = %q, want it to start with %q", stderr.String(), test.stderr)
+			}
+			if test.args[0] == "help" && stdout.String() != cliUsage {
+				t.Errorf("help printed %q", stdout.String())
+			}
+			if code == 2 && !strings.Contains(stderr.String(), cliUsage) {
+				t.Error("usage error didn't print the usage")
+			}
+
+			for _, name := range test.sessions {
+				if !f.has(name) {
+					t.Errorf("session %s missing, sessions %v", name, f.sessions)
+				}
+			}
+			if len(f.sessions) != len(test.sessions) {
+				t.Errorf("sessions = %v, want %v", f.sessions, test.sessions)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultCloneLayout = "{repo}"
+
+// CloneOptions tune how git clone fetches a repository. Depth, Branch and
+// Filter map to the flags of the same name; Sparse lists the directories to
+// check out, leaving the rest of the tree out of the working copy. Depth and
+// Submodules are pointers so that an explicit depth 0 (a full clone) or
+// false can turn off a default.
+type CloneOptions struct {
+	Depth      *int     `json:"depth,omitempty"`
+	Branch     string   `json:"branch,omitempty"`
+	Submodules *bool    `json:"recurse_submodules,omitempty"`
+	Filter     string   `json:"filter,omitempty"`
+	Sparse     []string `json:"sparse,omitempty"`
+}
+
+// merge returns o with the options set in other layered on top.
+func (o CloneOptions) merge(other CloneOptions) CloneOptions {
+	if other.Depth != nil {
+		o.Depth = other.Depth
+	}
+	if other.Branch != "" {
+		o.Branch = other.Branch
+	}
+	if other.Submodules != nil {
+		o.Submodules = other.Submodules
+	}
+	if other.Filter != "" {
+		o.Filter = other.Filter
+	}
+	if len(other.Sparse) > 0 {
+		o.Sparse = other.Sparse
+	}
+	return o
+}
+
+func (o CloneOptions) args() []string {
+	var args []string
+	shallow := o.Depth != nil && *o.Depth > 0
+	if shallow {
+		args = append(args, "--depth", strconv.Itoa(*o.Depth))
+	}
+	if o.Branch != "" {
+		args = append(args, "--branch", o.Branch)
+	}
+	if o.Submodules != nil && *o.Submodules {
+		args = append(args, "--recurse-submodules")
+		if shallow {
+			args = append(args, "--shallow-submodules")
+		}
+	}
+	if o.Filter != "" {
+		args = append(args, "--filter="+o.Filter)
+	}
+	if len(o.Sparse) > 0 {
+		args = append(args, "--sparse")
+	}
+	return args
+}
+
+// parseCloneRequest reads a repository URL, an optional directory name and
+// clone options, which may appear anywhere:
+//
+//	gh:owner/repo --depth 1 --branch v2.0 --sparse src,docs
+func parseCloneRequest(args []string) (string, string, CloneOptions, error) {
+	positional, opts, err := parseCloneArgs(args)
+	if err != nil {
+		return "", "", opts, err
+	}
+	switch len(positional) {
+	case 1:
+		return positional[0], "", opts, nil
+	case 2:
+		return positional[0], positional[1], opts, nil
+	}
+	return "", "", opts, fmt.Errorf("expected a repository URL and an optional name")
+}
+
+func parseCloneArgs(args []string) ([]string, CloneOptions, error) {
+	var positional []string
+	var opts CloneOptions
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flag, value, hasValue := strings.Cut(arg, "=")
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s needs a value", flag)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch flag {
+		case "--depth":
+			v, err := takeValue()
+			if err != nil {
+				return nil, opts, err
+			}
+			depth, err := strconv.Atoi(v)
+			if err != nil || depth < 0 {
+				return nil, opts, fmt.Errorf("invalid depth %q", v)
+			}
+			opts.Depth = &depth
+		case "--branch", "-b":
+			v, err := takeValue()
+			if err != nil {
+				return nil, opts, err
+			}
+			opts.Branch = v
+		case "--recurse-submodules", "--recursive":
+			submodules := true
+			opts.Submodules = &submodules
+		case "--no-recurse-submodules":
+			submodules := false
+			opts.Submodules = &submodules
+		case "--filter":
+			v, err := takeValue()
+			if err != nil {
+				return nil, opts, err
+			}
+			opts.Filter = v
+		case "--sparse":
+			v, err := takeValue()
+			if err != nil {
+				return nil, opts, err
+			}
+			for _, path := range strings.Split(v, ",") {
+				if path = strings.Trim(path, "/ "); path != "" {
+					opts.Sparse = append(opts.Sparse, path)
+				}
+			}
+		default:
+			return nil, opts, fmt.Errorf("unknown clone option %s", flag)
+		}
+	}
+	return positional, opts, nil
+}
+
+// cloneConflictError means the clone target already holds a different
+// repository, e.g. alice/utils when bob/utils was requested.
+type cloneConflictError struct {
+	target   string
+	existing string
+}
+
+func (e *cloneConflictError) Error() string {
+	if e.existing == "" {
+		return fmt.Sprintf("%s already exists and is not a clone of this repository", e.target)
+	}
+	return fmt.Sprintf("%s already contains a clone of %s", e.target, e.existing)
+}
+
+// cloneTarget expands the clone layout for remote under ReposPath. A non-empty
+// name replaces the last path element, for picking an alternate directory.
+// Targets that would end up outside ReposPath are rejected, as is a ReposPath
+// using an unset variable, which would put the clone under the current
+// directory.
+func cloneTarget(remote RemoteURL, config Config, name string) (string, error) {
+	if unset := unsetVariables(config.ReposPath); len(unset) > 0 {
+		return "", fmt.Errorf("repos path %s uses unset or empty variable %s", config.ReposPath, strings.Join(unset, ", "))
+	}
+
+	layout := config.CloneLayout
+	if layout == "" {
+		layout = defaultCloneLayout
+	}
+
+	relative := strings.NewReplacer(
+		"{host}", remote.Host,
+		"{owner}", remote.Owner,
+		"{repo}", remote.Repo,
+	).Replace(layout)
+
+	target := filepath.Join(config.ReposPath, filepath.FromSlash(relative))
+	if name != "" {
+		target = filepath.Join(filepath.Dir(target), name)
+	}
+
+	relative, err := filepath.Rel(config.ReposPath, target)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("clone target %s is outside %s", target, config.ReposPath)
+	}
+	return target, nil
+}
+
+func sameRemote(a, b RemoteURL) bool {
+	return a.Host == b.Host &&
+		strings.EqualFold(a.Owner, b.Owner) &&
+		strings.EqualFold(a.Repo, b.Repo)
+}
+
+func originURL(dir string) (string, error) {
+	output, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// checkCloneTarget reports whether target already holds a clone of remote.
+// It returns a *cloneConflictError when target exists but holds something
+// else.
+func checkCloneTarget(target string, remote RemoteURL) (bool, error) {
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	origin, err := originURL(target)
+	if err != nil {
+		return false, &cloneConflictError{target: target}
+	}
+	existing, err := parseRemoteURL(origin, nil)
+	if err != nil || !sameRemote(existing, remote) {
+		return false, &cloneConflictError{target: target, existing: origin}
+	}
+	return true, nil
+}
+
+func suggestCloneName(remote RemoteURL) string {
+	owner := remote.Owner
+	if i := strings.LastIndex(owner, "/"); i >= 0 {
+		owner = owner[i+1:]
+	}
+	return owner + "-" + remote.Repo
+}
+
+// cloneProgress is the latest progress report from git clone, such as
+// "Receiving objects" at 45 percent.
+type cloneProgress struct {
+	phase   string
+	percent int
+}
+
+var cloneProgressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z ]+):\s+(\d+)%`)
+
+func parseCloneProgress(line string) (cloneProgress, bool) {
+	match := cloneProgressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return cloneProgress{}, false
+	}
+	percent, _ := strconv.Atoi(match[2])
+	return cloneProgress{phase: match[1], percent: percent}, true
+}
+
+// lineWriter splits output into lines for onLine. git redraws its progress
+// with \r, so that ends a line too.
+type lineWriter struct {
+	buf    []byte
+	onLine func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\r' && b != '\n' {
+			w.buf = append(w.buf, b)
+			continue
+		}
+		if len(w.buf) > 0 {
+			w.onLine(string(w.buf))
+			w.buf = w.buf[:0]
+		}
+	}
+	return len(p), nil
+}
+
+// cloneRepo clones url into its place in the clone layout, or into name when
+// given, and returns the directory. An existing clone of the same repository
+// is reused. opts are layered over the configured defaults for the host.
+func cloneRepo(url string, config Config, name string, opts CloneOptions) (string, error) {
+	return cloneRepoContext(context.Background(), url, config, name, opts, nil)
+}
+
+// noPromptEnv is the environment for git commands run under the TUI, which
+// owns the terminal: git and ssh fail instead of asking for credentials or
+// to trust a host key.
+func noPromptEnv() []string {
+	sshCommand := os.Getenv("GIT_SSH_COMMAND")
+	if sshCommand == "" {
+		sshCommand = "ssh"
+	}
+	return append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND="+sshCommand+" -o BatchMode=yes")
+}
+
+// cloneRepoContext is cloneRepo reporting git's progress to progress, which
+// may be nil. Cancelling ctx stops git and removes the partial clone. With a
+// progress callback git runs under the TUI, so it gets noPromptEnv.
+func cloneRepoContext(ctx context.Context, url string, config Config, name string, opts CloneOptions, progress func(cloneProgress)) (string, error) {
+	remote, err := parseRemoteURL(url, config.RemoteShorthands)
+	if err != nil {
+		return "", fmt.Errorf("could not extract repository name from URL: %v", err)
+	}
+
+	targetDir, err := cloneTarget(remote, config, name)
+	if err != nil {
+		return "", err
+	}
+
+	exists, err := checkCloneTarget(targetDir, remote)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return targetDir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create repos directory: %v", err)
+	}
+
+	var lastLine string
+	stderr := &lineWriter{onLine: func(line string) {
+		if p, ok := parseCloneProgress(line); ok {
+			if progress != nil {
+				progress(p)
+			}
+			return
+		}
+		lastLine = strings.TrimSpace(line)
+	}}
+
+	opts = config.cloneDefaults(remote.Host).merge(opts)
+	args := append([]string{"clone", "--progress"}, opts.args()...)
+	args = append(args, remote.CloneURL, targetDir)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = stderr
+	if progress != nil {
+		cmd.Env = noPromptEnv()
+	}
+	// git's helpers may outlive a killed git and hold stderr open.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		// targetDir didn't exist before, so whatever is there is ours.
+		os.RemoveAll(targetDir)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if lastLine != "" {
+			return "", fmt.Errorf("failed to clone repository: %s", lastLine)
+		}
+		return "", fmt.Errorf("failed to clone repository: %v", err)
+	}
+
+	if len(opts.Sparse) > 0 {
+		if progress != nil {
+			progress(cloneProgress{phase: "Checking out sparse paths"})
+		}
+		sparse := exec.CommandContext(ctx, "git", append([]string{"-C", targetDir, "sparse-checkout", "set"}, opts.Sparse...)...)
+		if progress != nil {
+			sparse.Env = noPromptEnv()
+		}
+		if output, err := sparse.CombinedOutput(); err != nil {
+			os.RemoveAll(targetDir)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", fmt.Errorf("failed to set sparse-checkout paths: %s", strings.TrimSpace(string(output)))
+		}
+	}
+
+	return targetDir, nil
+}
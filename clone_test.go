@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCloneTarget(t *testing.T) {
+	config := Config{ReposPath: "/repos", CloneLayout: "{host}/{owner}/{repo}"}
+	remote := RemoteURL{Host: "github.com", Owner: "alice", Repo: "utils"}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", "/repos/github.com/alice/utils", false},
+		{"bob-utils", "/repos/github.com/alice/bob-utils", false},
+		{"../../../etc", "", true},
+		{"../../..", "", true},
+	}
+	for _, test := range tests {
+		got, err := cloneTarget(remote, config, test.name)
+		if (err != nil) != test.wantErr {
+			t.Errorf("cloneTarget(name %q) error = %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if got != filepath.FromSlash(test.want) {
+			t.Errorf("cloneTarget(name %q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCloneTargetRejectsTraversalInURL(t *testing.T) {
+	for _, url := range []string{
+		"https://host.example/../../etc/x",
+		"https://host.example/owner/%2e%2e",
+		"git@host.example:../repo.git",
+		"ssh://git@host.example/owner//repo",
+	} {
+		if remote, err := parseRemoteURL(url, nil); err == nil {
+			t.Errorf("parseRemoteURL(%q) = %+v, want an error", url, remote)
+		}
+	}
+}
+
+func TestCloneOptionsOptOut(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(`{"clone_defaults": {
+		"*": {"depth": 1, "recurse_submodules": true},
+		"full.example": {"depth": 0, "recurse_submodules": false}
+	}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		args []string
+		want []string
+	}{
+		{"github.com", nil, []string{"--depth", "1", "--recurse-submodules", "--shallow-submodules"}},
+		{"github.com", []string{"--depth", "0"}, []string{"--recurse-submodules"}},
+		{"github.com", []string{"--no-recurse-submodules"}, []string{"--depth", "1"}},
+		{"github.com", []string{"--depth=0", "--no-recurse-submodules"}, nil},
+		{"full.example", nil, nil},
+		{"full.example", []string{"--depth", "3", "--recursive"}, []string{"--depth", "3", "--recurse-submodules", "--shallow-submodules"}},
+	}
+	for _, test := range tests {
+		_, opts, err := parseCloneArgs(test.args)
+		if err != nil {
+			t.Fatalf("parseCloneArgs(%q): %v", test.args, err)
+		}
+		got := config.cloneDefaults(test.host).merge(opts).args()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: args %q, want %q", test.host, test.args, got, test.want)
+		}
+	}
+
+	if _, _, err := parseCloneArgs([]string{"--depth", "-1"}); err == nil {
+		t.Error("negative depth accepted")
+	}
+}
+
+func TestNoPromptEnv(t *testing.T) {
+	lastValue := func(env []string, name string) string {
+		value := ""
+		for _, entry := range env {
+			if v, ok := strings.CutPrefix(entry, name+"="); ok {
+				value = v
+			}
+		}
+		return value
+	}
+
+	t.Setenv("GIT_SSH_COMMAND", "")
+	env := noPromptEnv()
+	if got := lastValue(env, "GIT_TERMINAL_PROMPT"); got != "0" {
+		t.Errorf("GIT_TERMINAL_PROMPT = %q, want 0", got)
+	}
+	if got := lastValue(env, "GIT_SSH_COMMAND"); got != "ssh -o BatchMode=yes" {
+		t.Errorf("GIT_SSH_COMMAND = %q, want ssh -o BatchMode=yes", got)
+	}
+
+	t.Setenv("GIT_SSH_COMMAND", "ssh -i ~/.ssh/work")
+	if got := lastValue(noPromptEnv(), "GIT_SSH_COMMAND"); got != "ssh -i ~/.ssh/work -o BatchMode=yes" {
+		t.Errorf("GIT_SSH_COMMAND = %q, want the configured command in batch mode", got)
+	}
+}
+
+func TestCloneTargetRejectsUnsetVariable(t *testing.T) {
+	config := Config{ReposPath: "${MUX_SESH_UNSET}/repos", CloneLayout: "{host}/{owner}/{repo}"}
+	remote := RemoteURL{Host: "github.com", Owner: "alice", Repo: "utils"}
+
+	if target, err := cloneTarget(remote, config, ""); err == nil {
+		t.Errorf("cloneTarget = %q, want an error for the unset variable", target)
+	}
+}
@@ -2,27 +2,66 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	ProjectPaths []string `json:"project_paths"`
-	ReposPath    string   `json:"repos_path"`
-	Editor       string   `json:"editor"`
-	EditorCmd    string   `json:"editor_cmd"`
+	ProjectPaths    []ProjectPath     `json:"project_paths"`
+	Discovery       DiscoveryConfig   `json:"discovery"`
+	ReposPath       string            `json:"repos_path"`
+	CloneLayout     string            `json:"clone_layout"`
+	Editor          string            `json:"editor"`
+	EditorCmd       string            `json:"editor_cmd"`
+	EditorOverrides []EditorOverride  `json:"editor_overrides,omitempty"`
+	Layouts         map[string]Layout `json:"layouts,omitempty"`
+	// RemoteShorthands maps a prefix such as "gh" to a URL template, so
+	// "gh:owner/repo" clones {path} = owner/repo from that host.
+	RemoteShorthands map[string]string `json:"remote_shorthands,omitempty"`
+	// CloneDefaults holds clone options per host, with "*" applying to
+	// every host.
+	CloneDefaults map[string]CloneOptions `json:"clone_defaults,omitempty"`
+	// PanePreview starts with the live preview of the selected session's
+	// active pane shown instead of its window list.
+	PanePreview bool `json:"pane_preview,omitempty"`
+	// RestoreCommands are the programs restoring a snapshot starts again;
+	// "*" restarts every recorded command.
+	RestoreCommands []string `json:"restore_commands,omitempty"`
+	// AutoSnapshot controls the snapshots taken by the daemon and by
+	// `save --auto` from tmux hooks.
+	AutoSnapshot AutoSnapshotConfig `json:"auto_snapshot"`
+	// ConfirmKill asks before d kills the highlighted session. Killing
+	// selected sessions always asks.
+	ConfirmKill bool `json:"confirm_kill"`
+}
+
+// EditorOverride replaces editor_cmd for projects whose path matches Match.
+// An empty Command opens a plain shell.
+type EditorOverride struct {
+	Match   string `json:"match"`
+	Command string `json:"command"`
 }
 
 func DefaultConfig() Config {
 	homeDir := os.Getenv("HOME")
 	return Config{
-		ProjectPaths: []string{
-			filepath.Join(homeDir, "dev"),
-			filepath.Join(homeDir, "personal"),
+		ProjectPaths: []ProjectPath{
+			{Path: filepath.Join(homeDir, "dev")},
+			{Path: filepath.Join(homeDir, "personal")},
 		},
-		ReposPath: filepath.Join(homeDir, "dev", "repos"),
-		Editor:    "nvim",
-		EditorCmd: "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"",
+		Discovery:        DefaultDiscoveryConfig(),
+		RemoteShorthands: DefaultRemoteShorthands(),
+		ReposPath:        filepath.Join(homeDir, "dev", "repos"),
+		CloneLayout:      defaultCloneLayout,
+		RestoreCommands:  DefaultRestoreCommands(),
+		AutoSnapshot:     DefaultAutoSnapshotConfig(),
+		ConfirmKill:      true,
+		Editor:           "nvim",
+		EditorCmd:        "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"",
 	}
 }
 
@@ -46,6 +85,9 @@ func LoadConfig() Config {
 		return DefaultConfig()
 	}
 
+	var keys map[string]json.RawMessage
+	json.Unmarshal(data, &keys)
+
 	if len(config.ProjectPaths) == 0 {
 		config.ProjectPaths = DefaultConfig().ProjectPaths
 	}
@@ -55,9 +97,49 @@ func LoadConfig() Config {
 	if config.Editor == "" {
 		config.Editor = DefaultConfig().Editor
 	}
-	if config.EditorCmd == "" {
-		config.EditorCmd = DefaultConfig().EditorCmd
+	if config.CloneLayout == "" {
+		config.CloneLayout = DefaultConfig().CloneLayout
+	}
+	if config.RestoreCommands == nil {
+		config.RestoreCommands = DefaultConfig().RestoreCommands
+	}
+	if config.AutoSnapshot.Interval == "" {
+		config.AutoSnapshot.Interval = DefaultAutoSnapshotConfig().Interval
+	}
+	if config.AutoSnapshot.Keep == 0 {
+		config.AutoSnapshot.Keep = DefaultAutoSnapshotConfig().Keep
+	}
+	shorthands := DefaultRemoteShorthands()
+	for prefix, template := range config.RemoteShorthands {
+		shorthands[prefix] = template
+	}
+	config.RemoteShorthands = shorthands
+	if config.Discovery.MaxDepth <= 0 {
+		config.Discovery.MaxDepth = DefaultDiscoveryConfig().MaxDepth
 	}
+	if config.Discovery.Ignore == nil {
+		config.Discovery.Ignore = DefaultDiscoveryConfig().Ignore
+	}
+	if config.Discovery.Timeout == "" {
+		config.Discovery.Timeout = DefaultDiscoveryConfig().Timeout
+	}
+	if config.Discovery.Markers == nil {
+		config.Discovery.Markers = DefaultDiscoveryConfig().Markers
+	}
+	if _, ok := keys["confirm_kill"]; !ok {
+		config.ConfirmKill = DefaultConfig().ConfirmKill
+	}
+	// An explicit empty editor_cmd means "start a plain shell"; only fill it
+	// in when the key is missing.
+	if _, ok := keys["editor_cmd"]; !ok {
+		if config.Editor != DefaultConfig().Editor {
+			config.EditorCmd = config.Editor
+		} else {
+			config.EditorCmd = DefaultConfig().EditorCmd
+		}
+	}
+
+	config.expandPaths()
 
 	return config
 }
@@ -78,3 +160,162 @@ func SaveConfig(config Config) error {
 func GetConfigPath() string {
 	return filepath.Join(os.Getenv("HOME"), ".config", "mux-sesh", "config.json")
 }
+
+func (c Config) startupCommand(projectPath string) string {
+	for _, override := range c.EditorOverrides {
+		if matchPathGlob(override.Match, projectPath) {
+			return override.Command
+		}
+	}
+	return c.EditorCmd
+}
+
+// matchPathGlob reports whether path, or any directory above it, matches the
+// glob pattern, so "~/work/*" also covers projects nested deeper in ~/work.
+func matchPathGlob(pattern, path string) bool {
+	for path != "" {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	return false
+}
+
+var xdgDefaults = map[string]string{
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_DATA_HOME":   filepath.Join(".local", "share"),
+	"XDG_STATE_HOME":  filepath.Join(".local", "state"),
+	"XDG_CACHE_HOME":  ".cache",
+}
+
+// expandPath resolves a leading ~ and $VAR / ${VAR} references. Unset XDG
+// base directory variables fall back to their spec defaults under $HOME;
+// other unset or empty variables are left as ${VAR} so Validate can report
+// them.
+func expandPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	homeDir := os.Getenv("HOME")
+
+	if path == "~" {
+		path = homeDir
+	} else if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(homeDir, path[2:])
+	}
+
+	path = os.Expand(path, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			return value
+		}
+		if dir, ok := xdgDefaults[name]; ok {
+			return filepath.Join(homeDir, dir)
+		}
+		return "${" + name + "}"
+	})
+
+	return filepath.Clean(path)
+}
+
+var unsetVariablePattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// unsetVariables lists the ${VAR} references expandPath left in path.
+func unsetVariables(path string) []string {
+	var names []string
+	for _, match := range unsetVariablePattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+func (c *Config) expandPaths() {
+	for i, root := range c.ProjectPaths {
+		c.ProjectPaths[i].Path = expandPath(root.Path)
+		c.ProjectPaths[i].Ignore = expandPatterns(root.Ignore)
+	}
+	c.Discovery.Ignore = expandPatterns(c.Discovery.Ignore)
+	c.ReposPath = expandPath(c.ReposPath)
+
+	for i, override := range c.EditorOverrides {
+		c.EditorOverrides[i].Match = expandPath(override.Match)
+	}
+	for name, layout := range c.Layouts {
+		for i, pattern := range layout.Match {
+			layout.Match[i] = expandPath(pattern)
+		}
+		c.Layouts[name] = layout
+	}
+}
+
+// expandPatterns expands ignore globs that refer to a path rather than a
+// bare directory name.
+func expandPatterns(patterns []string) []string {
+	for i, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			patterns[i] = expandPath(pattern)
+		}
+	}
+	return patterns
+}
+
+// Validate reports configured paths that can't be used, so a typo in
+// project_paths shows up instead of producing an empty project list.
+func (c Config) Validate() []string {
+	var problems []string
+
+	for _, root := range c.ProjectPaths {
+		path := root.Path
+		info, err := os.Stat(path)
+		if unset := unsetVariables(path); len(unset) > 0 {
+			problems = append(problems, fmt.Sprintf("project path %s uses unset or empty variable %s", path, strings.Join(unset, ", ")))
+		} else if os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("project path %s does not exist", path))
+		} else if err != nil {
+			problems = append(problems, fmt.Sprintf("project path %s: %v", path, err))
+		} else if !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("project path %s is not a directory", path))
+		}
+	}
+
+	for _, root := range c.ProjectPaths {
+		if _, err := time.ParseDuration(root.Timeout); root.Timeout != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("project path %s has invalid timeout %q", root.Path, root.Timeout))
+		}
+	}
+	if _, err := time.ParseDuration(c.Discovery.Timeout); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid discovery timeout %q", c.Discovery.Timeout))
+	}
+
+	if interval, err := time.ParseDuration(c.AutoSnapshot.Interval); err != nil || interval <= 0 {
+		problems = append(problems, fmt.Sprintf("invalid auto snapshot interval %q", c.AutoSnapshot.Interval))
+	}
+	if c.AutoSnapshot.Keep < 0 {
+		problems = append(problems, fmt.Sprintf("auto snapshot keep must be positive, got %d", c.AutoSnapshot.Keep))
+	}
+
+	for host, opts := range c.CloneDefaults {
+		if opts.Depth != nil && *opts.Depth < 0 {
+			problems = append(problems, fmt.Sprintf("clone defaults for %s have negative depth %d", host, *opts.Depth))
+		}
+	}
+
+	if unset := unsetVariables(c.ReposPath); len(unset) > 0 {
+		problems = append(problems, fmt.Sprintf("repos path %s uses unset or empty variable %s", c.ReposPath, strings.Join(unset, ", ")))
+	} else if info, err := os.Stat(c.ReposPath); err == nil && !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("repos path %s is not a directory", c.ReposPath))
+	}
+
+	return problems
+}
+
+// cloneDefaults returns the configured clone options for host, layering the
+// host's own entry over the "*" entry.
+func (c Config) cloneDefaults(host string) CloneOptions {
+	return c.CloneDefaults["*"].merge(c.CloneDefaults[host])
+}
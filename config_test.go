@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("CODE", "/srv/code")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("EMPTY", "")
+
+	tests := map[string]string{
+		"~":                      "/home/me",
+		"~/dev":                  "/home/me/dev",
+		"$CODE/go":               "/srv/code/go",
+		"${CODE}/go":             "/srv/code/go",
+		"${XDG_DATA_HOME}/repos": "/home/me/.local/share/repos",
+		"$MUX_SESH_UNSET/dev":    "${MUX_SESH_UNSET}/dev",
+		"${MUX_SESH_UNSET}/dev":  "${MUX_SESH_UNSET}/dev",
+		"$EMPTY/dev":             "${EMPTY}/dev",
+		"/plain/path/../other":   "/plain/other",
+		"":                       "",
+	}
+	for path, want := range tests {
+		if got := expandPath(path); got != want {
+			t.Errorf("expandPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestValidateReportsUnsetVariables(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.ProjectPaths = []ProjectPath{{Path: expandPath("$MUX_SESH_UNSET/dev")}, {Path: dir}}
+	config.ReposPath = expandPath("${MUX_SESH_UNSET}/repos")
+
+	problems := config.Validate()
+	want := []string{
+		"project path ${MUX_SESH_UNSET}/dev uses unset or empty variable MUX_SESH_UNSET",
+		"repos path ${MUX_SESH_UNSET}/repos uses unset or empty variable MUX_SESH_UNSET",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Validate() = %q, want %q", problems, want)
+	}
+	if got := unsetVariables(filepath.Join(dir, "x")); got != nil {
+		t.Errorf("unsetVariables on a plain path = %q", got)
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProjectPath is an entry of project_paths. It is written either as a plain
+// path string or as an object overriding the discovery settings for one root.
+type ProjectPath struct {
+	Path           string   `json:"path"`
+	MaxDepth       int      `json:"max_depth,omitempty"`
+	Ignore         []string `json:"ignore,omitempty"`
+	FollowSymlinks *bool    `json:"follow_symlinks,omitempty"`
+	Timeout        string   `json:"timeout,omitempty"`
+}
+
+type projectPathFields ProjectPath
+
+func (p *ProjectPath) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*p = ProjectPath{Path: path}
+		return nil
+	}
+
+	var fields projectPathFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*p = ProjectPath(fields)
+	return nil
+}
+
+func (p ProjectPath) MarshalJSON() ([]byte, error) {
+	if p.MaxDepth == 0 && len(p.Ignore) == 0 && p.FollowSymlinks == nil && p.Timeout == "" {
+		return json.Marshal(p.Path)
+	}
+	return json.Marshal(projectPathFields(p))
+}
+
+type DiscoveryConfig struct {
+	MaxDepth       int      `json:"max_depth"`
+	Ignore         []string `json:"ignore"`
+	FollowSymlinks bool     `json:"follow_symlinks"`
+	Timeout        string   `json:"timeout"`
+	Markers        []string `json:"markers"`
+}
+
+func DefaultDiscoveryConfig() DiscoveryConfig {
+	return DiscoveryConfig{
+		MaxDepth: 3,
+		Ignore:   []string{".*", "node_modules", "target", "build", "dist"},
+		Timeout:  "5s",
+		Markers:  []string{".git", "go.mod", "package.json", "Cargo.toml", ".mux-sesh.yml"},
+	}
+}
+
+type walkOptions struct {
+	maxDepth       int
+	ignore         []string
+	followSymlinks bool
+	timeout        time.Duration
+	markers        []string
+}
+
+func (d DiscoveryConfig) optionsFor(root ProjectPath) walkOptions {
+	opts := walkOptions{
+		maxDepth:       d.MaxDepth,
+		ignore:         d.Ignore,
+		followSymlinks: d.FollowSymlinks,
+		markers:        d.Markers,
+	}
+	opts.timeout, _ = time.ParseDuration(d.Timeout)
+
+	if root.MaxDepth > 0 {
+		opts.maxDepth = root.MaxDepth
+	}
+	if len(root.Ignore) > 0 {
+		opts.ignore = root.Ignore
+	}
+	if root.FollowSymlinks != nil {
+		opts.followSymlinks = *root.FollowSymlinks
+	}
+	if timeout, err := time.ParseDuration(root.Timeout); err == nil {
+		opts.timeout = timeout
+	}
+	return opts
+}
+
+const maxConcurrentReads = 16
+
+type project struct {
+	path string
+	kind string
+}
+
+// walker walks one root. visited holds the real path of every directory
+// walked, so a symlink to one of them isn't walked again under a second path.
+type walker struct {
+	ctx     context.Context
+	opts    walkOptions
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	found   []project
+	dirs    map[string]int64
+	visited map[string]bool
+	links   []symlink
+}
+
+// symlink is a link to follow once the directories themselves are walked.
+type symlink struct {
+	path  string
+	depth int
+}
+
+// rootScan is the result of walking one root. dirs holds the modification
+// time of every directory that was read, which is what the project cache
+// compares to decide whether the root needs walking again.
+type rootScan struct {
+	projects []project
+	dirs     map[string]int64
+	complete bool
+}
+
+// walkRoot finds the projects below root up to the configured depth, reading
+// directories concurrently. When the timeout expires it returns whatever was
+// found so far and marks the scan incomplete.
+func walkRoot(root string, opts walkOptions) rootScan {
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	w := &walker{
+		ctx:     ctx,
+		opts:    opts,
+		sem:     make(chan struct{}, maxConcurrentReads),
+		dirs:    map[string]int64{},
+		visited: map[string]bool{},
+	}
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		real = root
+	}
+	w.visited[real] = true
+
+	// Symlinks are followed in rounds after the directories they were found
+	// in, so the real directory always wins over a link to it.
+	w.wg.Add(1)
+	go w.walk(root, real, 0)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			w.wg.Wait()
+			if !w.followLinks() {
+				break
+			}
+		}
+		close(done)
+	}()
+
+	complete := true
+	select {
+	case <-done:
+	case <-ctx.Done():
+		complete = false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dirs := make(map[string]int64, len(w.dirs))
+	for dir, mtime := range w.dirs {
+		dirs[dir] = mtime
+	}
+	return rootScan{
+		projects: append([]project(nil), w.found...),
+		dirs:     dirs,
+		complete: complete,
+	}
+}
+
+// walk reads dir, whose real path is real, and records it when it is a
+// project. Project roots are not descended into; without markers every
+// directory counts as a project.
+func (w *walker) walk(dir, real string, depth int) {
+	defer w.wg.Done()
+
+	select {
+	case w.sem <- struct{}{}:
+	case <-w.ctx.Done():
+		return
+	}
+	info, statErr := os.Stat(dir)
+	entries, err := os.ReadDir(dir)
+	<-w.sem
+	if err != nil || statErr != nil {
+		return
+	}
+
+	w.mu.Lock()
+	w.dirs[dir] = info.ModTime().UnixNano()
+	w.mu.Unlock()
+
+	if depth > 0 {
+		kind, isProject := detectProject(entries, w.opts.markers)
+		if isProject || len(w.opts.markers) == 0 {
+			w.mu.Lock()
+			w.found = append(w.found, project{path: dir, kind: kind})
+			w.mu.Unlock()
+		}
+		if isProject || depth >= w.opts.maxDepth {
+			return
+		}
+	}
+
+	for _, entry := range entries {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if w.ignored(entry.Name(), path) {
+			continue
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			if w.opts.followSymlinks {
+				w.mu.Lock()
+				w.links = append(w.links, symlink{path: path, depth: depth + 1})
+				w.mu.Unlock()
+			}
+			continue
+		}
+		if !entry.IsDir() || !w.visit(filepath.Join(real, entry.Name())) {
+			continue
+		}
+
+		w.wg.Add(1)
+		go w.walk(path, filepath.Join(real, entry.Name()), depth+1)
+	}
+}
+
+var projectKinds = map[string]string{
+	".git":           "git",
+	".mux-sesh.yml":  "mux-sesh",
+	".mux-sesh.yaml": "mux-sesh",
+	"go.mod":         "go",
+	"package.json":   "node",
+	"deno.json":      "deno",
+	"Cargo.toml":     "rust",
+	"pyproject.toml": "python",
+	"setup.py":       "python",
+	"Gemfile":        "ruby",
+	"pom.xml":        "java",
+	"build.gradle":   "java",
+	"mix.exs":        "elixir",
+	"composer.json":  "php",
+	"CMakeLists.txt": "cmake",
+	"flake.nix":      "nix",
+	"Makefile":       "make",
+}
+
+// genericKinds say a directory is a project without saying what kind, so a
+// more specific marker in the same directory wins over them.
+var genericKinds = map[string]bool{
+	"git":      true,
+	"mux-sesh": true,
+}
+
+func detectProject(entries []os.DirEntry, markers []string) (string, bool) {
+	kind := ""
+	isProject := false
+
+	for _, marker := range markers {
+		for _, entry := range entries {
+			if matched, _ := filepath.Match(marker, entry.Name()); !matched {
+				continue
+			}
+
+			isProject = true
+			label, ok := projectKinds[marker]
+			if !ok {
+				label = strings.TrimPrefix(marker, ".")
+			}
+			if kind == "" || genericKinds[kind] {
+				kind = label
+			}
+			break
+		}
+	}
+	return kind, isProject
+}
+
+// visit marks a directory, by its real path, as walked and reports whether
+// it hadn't been already.
+func (w *walker) visit(real string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.visited[real] {
+		return false
+	}
+	w.visited[real] = true
+	return true
+}
+
+// followLinks starts walking the pending symlinks that point at directories
+// not walked yet, in path order so the same link wins every time, and reports
+// whether it started any. Skipping walked directories also keeps link cycles
+// from recursing forever.
+func (w *walker) followLinks() bool {
+	w.mu.Lock()
+	links := w.links
+	w.links = nil
+	w.mu.Unlock()
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].path < links[j].path
+	})
+
+	started := false
+	for _, link := range links {
+		if w.ctx.Err() != nil {
+			break
+		}
+		info, err := os.Stat(link.path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(link.path)
+		if err != nil || !w.visit(real) {
+			continue
+		}
+		w.wg.Add(1)
+		go w.walk(link.path, real, link.depth)
+		started = true
+	}
+	return started
+}
+
+func (w *walker) ignored(name, path string) bool {
+	return ignored(name, path, w.opts.ignore)
+}
+
+// ignored matches a directory against ignore globs; patterns containing a
+// slash match the full path, others just the name.
+func ignored(name, path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = path
+		}
+		if matched, _ := filepath.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverProjects walks every configured root, reusing cached results for
+// roots whose directories haven't changed, and returns the projects together
+// with an updated cache.
+func discoverProjects(config Config, cache projectCache) ([]project, projectCache) {
+	scans := make([]rootScan, len(config.ProjectPaths))
+
+	var wg sync.WaitGroup
+	for i, root := range config.ProjectPaths {
+		// A root with an unset variable would be walked relative to the
+		// current directory; Validate reports it instead.
+		if len(unsetVariables(root.Path)) > 0 {
+			continue
+		}
+		if info, err := os.Stat(root.Path); err != nil || !info.IsDir() {
+			continue
+		}
+
+		opts := config.Discovery.optionsFor(root)
+		if scan, ok := cache.lookup(root.Path, opts, true); ok {
+			scans[i] = scan
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, root string, opts walkOptions) {
+			defer wg.Done()
+			scans[i] = walkRoot(root, opts)
+		}(i, root.Path, opts)
+	}
+	wg.Wait()
+
+	updated := newProjectCache()
+	var found [][]project
+	for i, root := range config.ProjectPaths {
+		scan := scans[i]
+		if scan.complete {
+			updated.store(root.Path, config.Discovery.optionsFor(root), scan)
+		}
+		found = append(found, scan.projects)
+	}
+
+	return mergeProjects(found), updated
+}
+
+// cachedProjects returns what the cache knows about the configured roots
+// without touching the filesystem, for showing something immediately.
+func cachedProjects(config Config, cache projectCache) []project {
+	var found [][]project
+	for _, root := range config.ProjectPaths {
+		if scan, ok := cache.lookup(root.Path, config.Discovery.optionsFor(root), false); ok {
+			found = append(found, scan.projects)
+		}
+	}
+	return mergeProjects(found)
+}
+
+func mergeProjects(found [][]project) []project {
+	seen := map[string]bool{}
+	var projects []project
+	for _, scanned := range found {
+		for _, p := range scanned {
+			if !seen[p.path] {
+				seen[p.path] = true
+				projects = append(projects, p)
+			}
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].path < projects[j].path
+	})
+	return projects
+}
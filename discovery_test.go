@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverProjectsSkipsUnsetVariables(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "${MUX_SESH_UNSET}", "app", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.ProjectPaths = []ProjectPath{{Path: "${MUX_SESH_UNSET}"}}
+	if projects, _ := discoverProjects(config, newProjectCache()); len(projects) > 0 {
+		t.Errorf("discoverProjects walked the unexpanded root: %v", projects)
+	}
+}
+
+func TestWalkRootListsLinkedProjectsOnce(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(root, "work", "app", ".git"),
+		filepath.Join(outside, "lib", ".git"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(root, "0-work"):       filepath.Join(root, "work"),
+		filepath.Join(root, "work", "loop"): root,
+		filepath.Join(root, "ext"):          outside,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DefaultConfig().Discovery.optionsFor(ProjectPath{Path: root})
+	opts.followSymlinks = true
+	scan := walkRoot(root, opts)
+
+	var paths []string
+	for _, p := range mergeProjects([][]project{scan.projects}) {
+		paths = append(paths, p.path)
+	}
+	want := []string{filepath.Join(root, "ext", "lib"), filepath.Join(root, "work", "app")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("projects = %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scoring follows fzf: every matched character is worth scoreMatch, gaps
+// cost a start penalty plus a smaller per-character extension, and
+// characters at word starts, path components or camelCase humps earn a
+// bonus. The first pattern character's bonus counts double.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary          = scoreMatch / 2
+	bonusBoundaryWhite     = bonusBoundary + 2
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	bonusCamel             = bonusBoundary - 1
+	bonusConsecutive       = -(scoreGapStart + scoreGapExtension)
+	bonusFirstCharFactor   = 2
+)
+
+type charClass int
+
+const (
+	classWhite charClass = iota
+	classDelimiter
+	classNonWord
+	classLower
+	classUpper
+	classLetter
+	classNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case unicode.IsSpace(r):
+		return classWhite
+	case r == '/' || r == '\\':
+		return classDelimiter
+	case unicode.IsLower(r):
+		return classLower
+	case unicode.IsUpper(r):
+		return classUpper
+	case unicode.IsLetter(r):
+		return classLetter
+	case unicode.IsNumber(r):
+		return classNumber
+	default:
+		return classNonWord
+	}
+}
+
+func isWordClass(c charClass) bool {
+	return c >= classLower
+}
+
+func positionBonus(prev, cur charClass) int {
+	if !isWordClass(cur) {
+		if cur == classDelimiter || cur == classWhite {
+			return bonusBoundary
+		}
+		return 0
+	}
+
+	switch {
+	case prev == classWhite:
+		return bonusBoundaryWhite
+	case prev == classDelimiter:
+		return bonusBoundaryDelimiter
+	case !isWordClass(prev):
+		return bonusBoundary
+	case prev == classLower && cur == classUpper:
+		return bonusCamel
+	case prev != classNumber && cur == classNumber:
+		return bonusCamel
+	}
+	return 0
+}
+
+func hasUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyMatch finds pattern as a subsequence of text and returns the best
+// score and the rune indices of the matched characters. Matching is
+// case-insensitive unless pattern contains an upper-case letter.
+func fuzzyMatch(text, pattern string) (int, []int, bool) {
+	pat := []rune(pattern)
+	if len(pat) == 0 {
+		return 0, nil, true
+	}
+	txt := []rune(text)
+	n, m := len(txt), len(pat)
+	if m > n {
+		return 0, nil, false
+	}
+
+	caseSensitive := hasUpper(pattern)
+	fold := func(r rune) rune {
+		if caseSensitive {
+			return r
+		}
+		return unicode.ToLower(r)
+	}
+	for i := range pat {
+		pat[i] = fold(pat[i])
+	}
+
+	if !isSubsequence(txt, pat, fold) {
+		return 0, nil, false
+	}
+
+	bonus := make([]int, n)
+	prev := classWhite
+	for j, r := range txt {
+		cur := classOf(r)
+		bonus[j] = positionBonus(prev, cur)
+		prev = cur
+	}
+
+	const none = -1 << 30
+	score := make([][]int, m)
+	from := make([][]int, m)
+	for i := range score {
+		score[i] = make([]int, n)
+		from[i] = make([]int, n)
+		for j := range score[i] {
+			score[i][j] = none
+			from[i][j] = -1
+		}
+	}
+
+	for i := 0; i < m; i++ {
+		// gapBest is the best score of pattern[i-1] ending at least two
+		// characters back, already charged for the gap up to j.
+		gapBest, gapFrom := none, -1
+		for j := i; j < n; j++ {
+			if i > 0 && j >= 2 && score[i-1][j-2] != none {
+				if gapBest != none {
+					gapBest += scoreGapExtension
+				}
+				if candidate := score[i-1][j-2] + scoreGapStart; candidate > gapBest {
+					gapBest, gapFrom = candidate, j-2
+				}
+			} else if gapBest != none {
+				gapBest += scoreGapExtension
+			}
+
+			if fold(txt[j]) != pat[i] {
+				continue
+			}
+
+			if i == 0 {
+				score[i][j] = scoreMatch + bonus[j]*bonusFirstCharFactor
+				continue
+			}
+
+			if j > 0 && score[i-1][j-1] != none {
+				consecutive := bonus[j]
+				if consecutive < bonusConsecutive {
+					consecutive = bonusConsecutive
+				}
+				score[i][j] = score[i-1][j-1] + scoreMatch + consecutive
+				from[i][j] = j - 1
+			}
+			if gapBest != none {
+				if candidate := gapBest + scoreMatch + bonus[j]; candidate > score[i][j] {
+					score[i][j] = candidate
+					from[i][j] = gapFrom
+				}
+			}
+		}
+	}
+
+	best, end := none, -1
+	for j := m - 1; j < n; j++ {
+		if score[m-1][j] > best {
+			best, end = score[m-1][j], j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	positions := make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return best, positions, true
+}
+
+func isSubsequence(txt, pat []rune, fold func(rune) rune) bool {
+	i := 0
+	for _, r := range txt {
+		if i < len(pat) && fold(r) == pat[i] {
+			i++
+		}
+	}
+	return i == len(pat)
+}
+
+// fuzzyPositions marks every rune of text matched by any word of query.
+func fuzzyPositions(text, query string) map[int]bool {
+	marked := map[int]bool{}
+	for _, word := range splitQuery(query) {
+		if _, positions, ok := fuzzyMatch(text, word); ok {
+			for _, p := range positions {
+				marked[p] = true
+			}
+		}
+	}
+	return marked
+}
+
+func splitQuery(query string) []string {
+	return strings.Fields(query)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		text      string
+		pattern   string
+		ok        bool
+		positions []int
+	}{
+		{"mux-sesh", "msh", true, []int{0, 4, 7}},
+		{"mux-sesh", "", true, nil},
+		{"mux-sesh", "xyz", false, nil},
+		{"ms", "mux", false, nil},
+		{"dev/api-server", "as", true, []int{4, 8}},
+		{"fooBar", "fb", true, []int{0, 3}},
+		{"xxabc abc", "abc", true, []int{6, 7, 8}},
+		{"a-b-ab", "ab", true, []int{0, 2}},
+		{"MuxSesh", "MS", true, []int{0, 3}},
+		{"mux-sesh", "MS", false, nil},
+		{"MUX-SESH", "mux", true, []int{0, 1, 2}},
+		{"mux-sesh", "Mux", false, nil},
+		{"naïve-café", "nc", true, []int{0, 6}},
+	}
+	for _, test := range tests {
+		_, positions, ok := fuzzyMatch(test.text, test.pattern)
+		if ok != test.ok {
+			t.Errorf("fuzzyMatch(%q, %q) ok = %v, want %v", test.text, test.pattern, ok, test.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) positions = %v, want %v", test.text, test.pattern, positions, test.positions)
+		}
+	}
+}
+
+// TestFuzzyMatchRanking checks each bonus by scoring the same pattern
+// against a text that earns it and one that doesn't.
+func TestFuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		better, worse string
+	}{
+		{"word boundary", "fb", "foo-bar", "fooxbar"},
+		{"path component", "ab", "x/a/b", "xxaxb"},
+		{"camelCase", "fb", "fooBar", "foobar"},
+		{"consecutive", "abc", "xabcx", "xaxbxc"},
+		{"start of text", "mux", "mux-sesh", "tmux-sesh"},
+		{"shorter gap", "ab", "axb", "axxxxb"},
+	}
+	for _, test := range tests {
+		better, _, ok1 := fuzzyMatch(test.better, test.pattern)
+		worse, _, ok2 := fuzzyMatch(test.worse, test.pattern)
+		if !ok1 || !ok2 {
+			t.Errorf("%s: %q should match both %q and %q", test.name, test.pattern, test.better, test.worse)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("%s: %q scores %d on %q, not above %d on %q", test.name, test.pattern, better, test.better, worse, test.worse)
+		}
+	}
+}
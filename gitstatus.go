@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// gitStatus summarises the repository a directory belongs to. ok is false for
+// directories outside any repository.
+type gitStatus struct {
+	ok        bool
+	branch    string
+	ahead     int
+	behind    int
+	staged    int
+	modified  int
+	untracked int
+	conflicts int
+	subject   string
+}
+
+type gitStatusMsg struct {
+	dir    string
+	status gitStatus
+}
+
+// readGitStatus parses `git status --porcelain=v2 --branch` for dir and adds
+// the subject of the last commit.
+func readGitStatus(dir string) gitStatus {
+	output, err := exec.Command("git", "-C", dir, "status", "--porcelain=v2", "--branch").Output()
+	if err != nil {
+		return gitStatus{}
+	}
+
+	status := gitStatus{ok: true}
+	var oid string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "#":
+			if len(fields) < 3 {
+				continue
+			}
+			switch fields[1] {
+			case "branch.oid":
+				oid = fields[2]
+			case "branch.head":
+				status.branch = fields[2]
+			case "branch.ab":
+				if len(fields) == 4 {
+					status.ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+					status.behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+				}
+			}
+		case "1", "2":
+			if len(fields) < 2 || len(fields[1]) != 2 {
+				continue
+			}
+			if fields[1][0] != '.' {
+				status.staged++
+			}
+			if fields[1][1] != '.' {
+				status.modified++
+			}
+		case "u":
+			status.conflicts++
+		case "?":
+			status.untracked++
+		}
+	}
+
+	if status.branch == "(detached)" && len(oid) >= 7 {
+		status.branch = oid[:7]
+	}
+	if oid != "(initial)" {
+		if subject, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%s").Output(); err == nil {
+			status.subject = strings.TrimSpace(string(subject))
+		}
+	}
+	return status
+}
+
+func loadGitStatus(dir string) tea.Cmd {
+	return func() tea.Msg {
+		return gitStatusMsg{dir: dir, status: readGitStatus(dir)}
+	}
+}
+
+func (s gitStatus) clean() bool {
+	return s.staged == 0 && s.modified == 0 && s.untracked == 0 && s.conflicts == 0
+}
+
+// render formats the status as "⎇ main ↑1 ↓2 +3 ~1 ?2 !1".
+func (s gitStatus) render() string {
+	parts := []string{gitBranchStyle.Render("⎇ " + s.branch)}
+	if s.ahead > 0 {
+		parts = append(parts, gitAheadStyle.Render(fmt.Sprintf("↑%d", s.ahead)))
+	}
+	if s.behind > 0 {
+		parts = append(parts, gitAheadStyle.Render(fmt.Sprintf("↓%d", s.behind)))
+	}
+	if s.clean() {
+		parts = append(parts, gitStagedStyle.Render("✓"))
+	}
+	if s.staged > 0 {
+		parts = append(parts, gitStagedStyle.Render(fmt.Sprintf("+%d", s.staged)))
+	}
+	if s.modified > 0 {
+		parts = append(parts, gitModifiedStyle.Render(fmt.Sprintf("~%d", s.modified)))
+	}
+	if s.untracked > 0 {
+		parts = append(parts, inactiveIndicatorStyle.Render(fmt.Sprintf("?%d", s.untracked)))
+	}
+	if s.conflicts > 0 {
+		parts = append(parts, errorStyle.Render(fmt.Sprintf("!%d", s.conflicts)))
+	}
+	return strings.Join(parts, " ")
+}
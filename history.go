@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxHistoryRank bounds the summed rank of all entries. Once exceeded, every
+// rank is scaled down and entries that fall below 1 are forgotten, the same
+// aging zoxide uses.
+const maxHistoryRank = 10000
+
+type historyEntry struct {
+	Rank       float64 `json:"rank"`
+	LastAccess int64   `json:"last_access"`
+}
+
+// History remembers how often and how recently sessions and projects were
+// used, keyed by historyKey.
+type History struct {
+	Entries map[string]historyEntry `json:"entries"`
+}
+
+func historyPath() string {
+	return filepath.Join(expandPath("${XDG_DATA_HOME}"), "mux-sesh", "history.json")
+}
+
+func loadHistory() History {
+	history := History{Entries: map[string]historyEntry{}}
+
+	data, err := os.ReadFile(historyPath())
+	if err != nil {
+		return history
+	}
+	if err := json.Unmarshal(data, &history); err != nil || history.Entries == nil {
+		return History{Entries: map[string]historyEntry{}}
+	}
+	return history
+}
+
+func (h History) save() error {
+	if err := os.MkdirAll(filepath.Dir(historyPath()), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(historyPath(), data)
+}
+
+func sessionKey(name string) string {
+	return "session:" + name
+}
+
+func projectKey(path string) string {
+	return "project:" + path
+}
+
+func (i item) historyKey() string {
+	if i.isSession {
+		return sessionKey(i.title)
+	}
+	if i.isWindow || i.isPane {
+		return sessionKey(i.sessionName)
+	}
+	return projectKey(i.path)
+}
+
+func (h History) record(key string, now time.Time) {
+	entry := h.Entries[key]
+	entry.Rank++
+	entry.LastAccess = now.Unix()
+	h.Entries[key] = entry
+
+	total := 0.0
+	for _, e := range h.Entries {
+		total += e.Rank
+	}
+	if total <= maxHistoryRank {
+		return
+	}
+	for k, e := range h.Entries {
+		e.Rank *= 0.9
+		if e.Rank < 1 {
+			delete(h.Entries, k)
+		} else {
+			h.Entries[k] = e
+		}
+	}
+}
+
+func (h History) rename(oldKey, newKey string) {
+	if entry, ok := h.Entries[oldKey]; ok {
+		delete(h.Entries, oldKey)
+		h.Entries[newKey] = entry
+	}
+}
+
+func (h History) frecency(key string, now time.Time) float64 {
+	entry, ok := h.Entries[key]
+	if !ok {
+		return 0
+	}
+
+	age := now.Sub(time.Unix(entry.LastAccess, 0))
+	switch {
+	case age < time.Hour:
+		return entry.Rank * 4
+	case age < 24*time.Hour:
+		return entry.Rank * 2
+	case age < 7*24*time.Hour:
+		return entry.Rank / 2
+	default:
+		return entry.Rank / 4
+	}
+}
+
+// sort orders items by frecency, keeping the existing order among items
+// that have never been used.
+func (h History) sort(items []item) {
+	now := time.Now()
+	sort.SliceStable(items, func(i, j int) bool {
+		return h.frecency(items[i].historyKey(), now) > h.frecency(items[j].historyKey(), now)
+	})
+}
+
+// recordUse adds the given keys to the history on disk. It reloads the file
+// first so concurrent mux-sesh invocations don't overwrite each other.
+func recordUse(keys ...string) {
+	history := loadHistory()
+	now := time.Now()
+	for _, key := range keys {
+		history.record(key, now)
+	}
+	history.save()
+}
+
+func renameInHistory(oldName, newName string) {
+	history := loadHistory()
+	history.rename(sessionKey(oldName), sessionKey(newName))
+	history.save()
+}
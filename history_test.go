@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistorySave(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", "")
+
+	history := History{Entries: map[string]historyEntry{}}
+	history.record(sessionKey("work"), time.Unix(1700000000, 0))
+	history.record(projectKey("/src/app"), time.Unix(1700000100, 0))
+	if err := history.save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadHistory(); !reflect.DeepEqual(got, history) {
+		t.Errorf("loadHistory = %+v, want %+v", got, history)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(historyPath()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "history.json" {
+		t.Errorf("history dir holds %v, want only history.json", entries)
+	}
+}
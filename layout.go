@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var projectFileNames = []string{".mux-sesh.yml", ".mux-sesh.yaml"}
+
+type Layout struct {
+	Match   []string       `json:"match,omitempty" yaml:"match,omitempty"`
+	Windows []LayoutWindow `json:"windows" yaml:"windows"`
+}
+
+type LayoutWindow struct {
+	Name    string       `json:"name" yaml:"name"`
+	Dir     string       `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Command string       `json:"command,omitempty" yaml:"command,omitempty"`
+	Layout  string       `json:"layout,omitempty" yaml:"layout,omitempty"`
+	Focus   bool         `json:"focus,omitempty" yaml:"focus,omitempty"`
+	Panes   []LayoutPane `json:"panes,omitempty" yaml:"panes,omitempty"`
+}
+
+type LayoutPane struct {
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	Dir     string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Split   string `json:"split,omitempty" yaml:"split,omitempty"`
+	Size    string `json:"size,omitempty" yaml:"size,omitempty"`
+	Focus   bool   `json:"focus,omitempty" yaml:"focus,omitempty"`
+}
+
+// projectFile is the shape of .mux-sesh.yml: either an inline list of windows
+// or the name of a layout defined in the config.
+type projectFile struct {
+	Layout  string         `yaml:"layout"`
+	Windows []LayoutWindow `yaml:"windows"`
+}
+
+func loadProjectFile(projectPath string) (projectFile, bool, error) {
+	for _, name := range projectFileNames {
+		data, err := os.ReadFile(filepath.Join(projectPath, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return projectFile{}, false, err
+		}
+
+		var file projectFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return projectFile{}, false, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return file, true, nil
+	}
+	return projectFile{}, false, nil
+}
+
+func findLayout(config Config, projectPath string) (Layout, bool, error) {
+	file, ok, err := loadProjectFile(projectPath)
+	if err != nil {
+		return Layout{}, false, err
+	}
+	if ok {
+		if len(file.Windows) > 0 {
+			return Layout{Windows: file.Windows}, true, nil
+		}
+		if file.Layout != "" {
+			layout, found := config.Layouts[file.Layout]
+			if !found {
+				return Layout{}, false, fmt.Errorf("layout '%s' not found in config", file.Layout)
+			}
+			return layout, true, nil
+		}
+	}
+
+	// When several layouts match, the first by name wins, so the choice
+	// doesn't depend on map order.
+	names := make([]string, 0, len(config.Layouts))
+	for name := range config.Layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		layout := config.Layouts[name]
+		for _, pattern := range layout.Match {
+			if matchPathGlob(pattern, projectPath) {
+				return layout, true, nil
+			}
+		}
+	}
+	return Layout{}, false, nil
+}
+
+func resolveLayoutDir(root, dir string) string {
+	if dir == "" {
+		return root
+	}
+	if filepath.IsAbs(dir) || root == "" {
+		return dir
+	}
+	return filepath.Join(root, dir)
+}
+
+func (l Layout) firstDir(root string) string {
+	if len(l.Windows) == 0 {
+		return root
+	}
+	first := l.Windows[0]
+	dir := resolveLayoutDir(root, first.Dir)
+	if len(first.Panes) > 0 {
+		dir = resolveLayoutDir(dir, first.Panes[0].Dir)
+	}
+	return dir
+}
+
+func (w LayoutWindow) panes() []LayoutPane {
+	if len(w.Panes) > 0 {
+		return w.Panes
+	}
+	return []LayoutPane{{Command: w.Command}}
+}
+
+// applyLayout builds the windows and panes of a layout in a freshly created
+// session whose first window already exists. Windows are addressed by the id
+// of their first pane so later splits don't change what a target refers to.
+func applyLayout(t Tmux, sessionName, root string, layout Layout) error {
+	var focusWindow string
+
+	for i, window := range layout.Windows {
+		windowDir := resolveLayoutDir(root, window.Dir)
+		panes := window.panes()
+
+		var windowTarget string
+		if i == 0 {
+			target, err := t.ActivePane(sessionName + ":^")
+			if err != nil {
+				return fmt.Errorf("failed to find first window: %v", err)
+			}
+			windowTarget = target
+			if window.Name != "" {
+				if err := t.RenameWindow(windowTarget, window.Name); err != nil {
+					return fmt.Errorf("failed to rename window: %v", err)
+				}
+			}
+		} else {
+			target, err := t.NewWindow(sessionName, window.Name, resolveLayoutDir(windowDir, panes[0].Dir))
+			if err != nil {
+				return fmt.Errorf("failed to create window '%s': %v", window.Name, err)
+			}
+			windowTarget = target
+		}
+
+		var focusPane string
+		for j, pane := range panes {
+			paneTarget := windowTarget
+			if j > 0 {
+				target, err := t.SplitWindow(windowTarget, resolveLayoutDir(windowDir, pane.Dir), pane.Split == "horizontal", pane.Size)
+				if err != nil {
+					return fmt.Errorf("failed to split window '%s': %v", window.Name, err)
+				}
+				paneTarget = target
+			}
+
+			if pane.Command != "" {
+				t.SendKeys(paneTarget, pane.Command, "Enter")
+			}
+			if pane.Focus {
+				focusPane = paneTarget
+			}
+		}
+
+		if window.Layout != "" {
+			if err := t.SelectLayout(windowTarget, window.Layout); err != nil {
+				return fmt.Errorf("failed to select layout '%s': %v", window.Layout, err)
+			}
+		}
+		if focusPane != "" {
+			t.SelectPane(focusPane)
+		}
+		if window.Focus || (focusWindow == "" && i == 0) {
+			focusWindow = windowTarget
+		}
+	}
+
+	if focusWindow != "" {
+		t.SelectWindow(focusWindow)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestFindLayoutPicksFirstNameAmongMatches(t *testing.T) {
+	config := DefaultConfig()
+	config.Layouts = map[string]Layout{
+		"c-any":   {Match: []string{"/src/*"}, Windows: []LayoutWindow{{Name: "c"}}},
+		"a-web":   {Match: []string{"/src/web-*"}, Windows: []LayoutWindow{{Name: "a"}}},
+		"b-other": {Match: []string{"/src/web-app"}, Windows: []LayoutWindow{{Name: "b"}}},
+		"d-none":  {Match: []string{"/elsewhere/*"}, Windows: []LayoutWindow{{Name: "d"}}},
+	}
+
+	for i := 0; i < 20; i++ {
+		layout, ok, err := findLayout(config, "/src/web-app")
+		if err != nil || !ok {
+			t.Fatalf("findLayout = %v, %v", ok, err)
+		}
+		if layout.Windows[0].Name != "a" {
+			t.Fatalf("findLayout picked layout %q, want a-web", layout.Windows[0].Name)
+		}
+	}
+
+	if _, ok, _ := findLayout(config, "/other/app"); ok {
+		t.Error("findLayout matched a project no layout covers")
+	}
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -111,8 +111,42 @@ var (
 				Foreground(primaryColor).
 				Bold(true).
 				Align(lipgloss.Center)
+
+	messageStyle = lipgloss.NewStyle().
+			Foreground(keyColor)
+
+	projectKindStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#89dceb"))
+
+	markedStyle = lipgloss.NewStyle().
+			Foreground(keyColor).
+			Bold(true)
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(primaryColor).
+			Bold(true)
+
+	progressFilledStyle = lipgloss.NewStyle().
+				Foreground(activeColor)
+
+	progressEmptyStyle = lipgloss.NewStyle().
+				Foreground(separatorColor)
+
+	gitBranchStyle = lipgloss.NewStyle().
+			Foreground(actionColor)
+
+	gitAheadStyle = lipgloss.NewStyle().
+			Foreground(borderColor)
+
+	gitStagedStyle = lipgloss.NewStyle().
+			Foreground(activeColor)
+
+	gitModifiedStyle = lipgloss.NewStyle().
+				Foreground(keyColor)
 )
 
+const newSessionPlaceholder = "Type project name, repository URL, or custom session name..."
+
 type AppMode int
 
 const (
@@ -120,6 +154,11 @@ const (
 	ModeSearch
 	ModeNewSession
 	ModeRename
+	ModeNewWorktree
+	ModeCloneName
+	ModeCloning
+	ModeSnapshots
+	ModeConfirm
 )
 
 type ViewMode int
@@ -136,6 +175,13 @@ type item struct {
 	isSession   bool
 	isAttached  bool
 	windowCount string
+	kind        string
+	parent      string
+	sessionName string
+	isWorktree  bool
+	target      string
+	isWindow    bool
+	isPane      bool
 }
 
 type model struct {
@@ -153,11 +199,77 @@ type model struct {
 	height       int
 	message      string
 	renameTarget string
-	config       Config
+	sessionName  string
+	worktreeRepo string
+	worktrees    map[string][]item
+	cloneURL     string
+	cloneName    string
+	cloneInput   string
+	cloneOptions CloneOptions
+	cloneDir     string
+	clone        cloneProgress
+	cloneErr     error
+	cloneCancel  context.CancelFunc
+	cancelling   bool
+	cloneUpdates chan tea.Msg
+	// sessionWindows caches the windows of every session, loaded in one
+	// query and dropped on refresh.
+	sessionWindows map[string][]Window
+	sessionPanes   map[string][]Pane
+	// sessions is the session list before expanding; expanded holds the
+	// sessions and window targets whose children are shown.
+	sessions  []item
+	expanded  map[string]bool
+	snapshots []savedSnapshot
+	// selected holds the names of the sessions selected for a bulk
+	// action; confirmAction and confirmSessions are the action awaiting
+	// confirmation.
+	selected        map[string]bool
+	confirmAction   bulkAction
+	confirmSessions []string
+	// undo holds the sessions killed last until their undo expires.
+	undo           *killUndo
+	undoGeneration int
+	loadingDetails bool
+	panePreview    bool
+	paneSession    string
+	paneCapture    []string
+	// previewGeneration counts toggles of the pane preview; see
+	// panePreviewTickMsg.
+	previewGeneration int
+	// gitStatuses caches the git status of window directories; a nil
+	// entry is still loading.
+	gitStatuses map[string]*gitStatus
+	// previews caches project previews by path; nil while loading.
+	previews map[string]*projectPreview
+	config   Config
+	tmux     Tmux
+	scanning bool
+	history  History
+}
+
+type projectsScannedMsg struct {
+	items []item
+}
+
+type sessionDetailsMsg struct {
+	windows map[string][]Window
+	panes   map[string][]Pane
+}
+
+type cloneProgressMsg cloneProgress
+
+type cloneFinishedMsg struct {
+	path string
+	err  error
 }
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	cmds := []tea.Cmd{textinput.Blink, scanProjects(m.config), loadSessionDetails(m.tmux)}
+	if m.panePreview {
+		cmds = append(cmds, func() tea.Msg { return panePreviewTickMsg{generation: m.previewGeneration} })
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -169,38 +281,262 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case projectsScannedMsg:
+		m.scanning = false
+		m.setProjectItems(msg.items)
+		return m, m.loadSelectedPreview()
+
+	case cloneProgressMsg:
+		if !m.cancelling {
+			m.clone = cloneProgress(msg)
+		}
+		return m, waitForClone(m.cloneUpdates)
+
+	case cloneFinishedMsg:
+		m.cloneCancel = nil
+		m.cloneUpdates = nil
+		m.cancelling = false
+		switch {
+		case errors.Is(msg.err, context.Canceled):
+			m.leaveClone()
+			m.message = "Clone cancelled"
+			return m, nil
+		case msg.err != nil:
+			m.cloneErr = msg.err
+			return m, nil
+		}
+		m.choice = msg.path
+		m.action = "create"
+		return m, tea.Quit
+
+	case undoExpiredMsg:
+		if m.undo != nil && m.undo.generation == msg.generation {
+			m.undo = nil
+			m.message = strings.TrimSuffix(m.message, " (u to undo)")
+		}
+		return m, nil
+
+	case snapshotRestoredMsg:
+		m.appMode = ModeNormal
+		m.viewMode = ViewSessions
+		m.message = msg.summary()
+		return m, m.refreshItems()
+
+	case sessionDetailsMsg:
+		m.loadingDetails = false
+		m.sessionWindows = msg.windows
+		m.sessionPanes = msg.panes
+		if m.viewMode == ViewSessions && m.appMode == ModeNormal && len(m.expanded) > 0 {
+			m.allItems = m.sessionList()
+			m.items = m.allItems
+			if m.cursor >= len(m.items) {
+				m.cursor = max(0, len(m.items)-1)
+			}
+		}
+		return m, m.loadSelectedGitStatus()
+
+	case paneCaptureMsg:
+		if msg.session == m.paneSession {
+			m.paneCapture = panePreviewContent(msg.content)
+		}
+		return m, nil
+
+	case panePreviewTickMsg:
+		if !m.panePreview || msg.generation != m.previewGeneration {
+			return m, nil
+		}
+		m.paneSession = m.selectedSessionName()
+		var capture tea.Cmd
+		if m.paneSession != "" {
+			capture = capturePane(m.tmux, m.paneSession)
+		}
+		return m, tea.Batch(capture, panePreviewTick(msg.generation))
+
+	case gitStatusMsg:
+		if _, pending := m.gitStatuses[msg.dir]; pending {
+			m.gitStatuses[msg.dir] = &msg.status
+		}
+		return m, nil
+
+	case projectPreviewMsg:
+		if _, pending := m.previews[msg.path]; pending {
+			m.previews[msg.path] = &msg.preview
+		}
+		return m, nil
+
 	case tea.KeyMsg:
-		switch m.appMode {
-		case ModeNormal:
-			return m.handleNormalMode(msg)
-		case ModeSearch:
-			return m.handleSearchMode(msg)
-		case ModeNewSession:
-			return m.handleNewSessionMode(msg)
-		case ModeRename:
-			return m.handleRenameMode(msg)
+		next, cmd := m.handleKey(msg)
+		if next, ok := next.(model); ok {
+			gitCmd := next.loadSelectedGitStatus()
+			previewCmd := next.loadSelectedPreview()
+			captureCmd := next.captureMovedPane()
+			return next, tea.Batch(cmd, gitCmd, previewCmd, captureCmd)
 		}
+		return next, cmd
 	}
 
 	return m, cmd
 }
 
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.appMode {
+	case ModeNormal:
+		return m.handleNormalMode(msg)
+	case ModeSearch:
+		return m.handleSearchMode(msg)
+	case ModeNewSession:
+		return m.handleNewSessionMode(msg)
+	case ModeRename:
+		return m.handleRenameMode(msg)
+	case ModeNewWorktree:
+		return m.handleNewWorktreeMode(msg)
+	case ModeCloneName:
+		return m.handleCloneNameMode(msg)
+	case ModeCloning:
+		return m.handleCloningMode(msg)
+	case ModeSnapshots:
+		return m.handleSnapshotMode(msg)
+	case ModeConfirm:
+		return m.handleConfirmMode(msg)
+	}
+	return m, nil
+}
+
+// selectedSessionName is the session the selected item is or belongs to.
+func (m model) selectedSessionName() string {
+	if m.appMode != ModeNormal || m.choice != "" || m.cursor >= len(m.items) {
+		return ""
+	}
+	selected := m.items[m.cursor]
+	switch {
+	case selected.isSession:
+		return selected.title
+	case selected.isWindow, selected.isPane:
+		return selected.sessionName
+	}
+	return ""
+}
+
+// captureMovedPane captures the active pane of the selected session right
+// away when the selection moved, instead of waiting for the next tick.
+func (m *model) captureMovedPane() tea.Cmd {
+	session := m.selectedSessionName()
+	if !m.panePreview || session == m.paneSession {
+		return nil
+	}
+	m.paneSession = session
+	m.paneCapture = nil
+	if session == "" {
+		return nil
+	}
+	return capturePane(m.tmux, session)
+}
+
+// loadSelectedPreview starts loading the preview of the project highlighted
+// in the New Session view.
+func (m model) loadSelectedPreview() tea.Cmd {
+	if m.appMode != ModeNewSession || m.choice != "" || m.cursor >= len(m.items) {
+		return nil
+	}
+	selected := m.items[m.cursor]
+	if selected.isSession || selected.path == "" {
+		return nil
+	}
+	if _, seen := m.previews[selected.path]; seen {
+		return nil
+	}
+	m.previews[selected.path] = nil
+	return loadProjectPreview(m.tmux, selected, m.config.Discovery.Ignore)
+}
+
+// loadSelectedGitStatus starts loading the git status of the selected
+// session's window directories that aren't cached yet.
+func (m model) loadSelectedGitStatus() tea.Cmd {
+	session := m.selectedSessionName()
+	if session == "" {
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	for _, window := range m.sessionWindows[session] {
+		if _, seen := m.gitStatuses[window.Path]; seen || window.Path == "" {
+			continue
+		}
+		m.gitStatuses[window.Path] = nil
+		cmds = append(cmds, loadGitStatus(window.Path))
+	}
+	return tea.Batch(cmds...)
+}
+
 func (m model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch keypress := msg.String(); keypress {
-	case "ctrl+c", "q", "esc":
+	case "esc":
+		if len(m.selectedSessions()) > 0 {
+			m.selected = map[string]bool{}
+			return m, nil
+		}
+		m.quitting = true
+		return m, tea.Quit
+
+	case "ctrl+c", "q":
 		m.quitting = true
 		return m, tea.Quit
 
+	case " ":
+		if m.viewMode == ViewSessions && m.cursor < len(m.items) {
+			m.toggleSelected(m.items[m.cursor])
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+		}
+		return m, nil
+
+	case "a":
+		if m.viewMode == ViewSessions {
+			m.selectAll(m.items)
+		}
+		return m, nil
+
+	case "x":
+		if m.viewMode == ViewSessions {
+			return m.confirmBulkAction(bulkDetach)
+		}
+		return m, nil
+
+	case "w":
+		if m.viewMode == ViewSessions {
+			return m.confirmBulkAction(bulkSnapshot)
+		}
+		return m, nil
+
 	case "i":
 		m.appMode = ModeSearch
+		if m.viewMode == ViewSessions {
+			m.allItems = m.searchableSessions()
+			m.items = m.allItems
+			m.cursor = 0
+		}
 		m.searchInput.Focus()
 		m.searchInput.SetValue("")
 		return m, textinput.Blink
 
+	case "tab", "l", "right":
+		if m.viewMode == ViewSessions && m.cursor < len(m.items) {
+			m.toggleExpanded(m.items[m.cursor])
+		}
+		return m, nil
+
+	case "h", "left":
+		if m.viewMode == ViewSessions && m.cursor < len(m.items) {
+			m.collapseParent(m.items[m.cursor])
+		}
+		return m, nil
+
 	case "n":
 		m.appMode = ModeNewSession
 		m.viewMode = ViewProjects
-		m.allItems = m.projectItems
+		m.previews = map[string]*projectPreview{}
+		m.allItems = m.projectList()
 		m.items = m.allItems
 		if len(m.items) > 0 {
 			m.cursor = len(m.items) - 1
@@ -209,24 +545,27 @@ func (m model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.searchInput.Focus()
 		m.searchInput.SetValue("")
-		m.searchInput.Placeholder = "Type project name, GitHub URL, or custom session name..."
+		m.searchInput.Placeholder = newSessionPlaceholder
 		return m, textinput.Blink
 
 	case "d":
+		if m.viewMode == ViewSessions && len(m.selectedSessions()) > 0 {
+			return m.confirmBulkAction(bulkKill)
+		}
 		if m.viewMode == ViewSessions && len(m.items) > 0 && m.cursor < len(m.items) {
 			selectedItem := m.items[m.cursor]
 			if selectedItem.isSession {
-				err := killTmuxSession(selectedItem.title)
-				if err != nil {
-					m.message = fmt.Sprintf("Error killing session: %v", err)
-				} else {
-					m.message = fmt.Sprintf("Session '%s' killed", selectedItem.title)
-					m.refreshItems()
+				if m.config.ConfirmKill {
+					return m.confirmBulkAction(bulkKill)
 				}
+				return m, m.killSessions([]string{selectedItem.title})
 			}
 		}
 		return m, nil
 
+	case "u":
+		return m, m.undoKill()
+
 	case "r":
 		if m.viewMode == ViewSessions && len(m.items) > 0 && m.cursor < len(m.items) {
 			selectedItem := m.items[m.cursor]
@@ -241,30 +580,36 @@ func (m model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "v":
+		m.panePreview = !m.panePreview
+		m.paneSession = ""
+		m.paneCapture = nil
+		if !m.panePreview {
+			return m, nil
+		}
+		m.previewGeneration++
+		return m, panePreviewTick(m.previewGeneration)
+
+	case "S":
+		m.appMode = ModeSnapshots
+		m.loadSnapshots()
+		return m, nil
+
 	case "R":
-		m.refreshItems()
 		m.message = "Refreshed"
-		return m, nil
+		return m, m.refreshItems()
 
 	case "s":
 		m.viewMode = ViewSessions
-		m.refreshItems()
-		return m, nil
+		return m, m.refreshItems()
 
 	case "p":
 		m.viewMode = ViewProjects
-		m.refreshItems()
-		return m, nil
+		return m, m.refreshItems()
 
 	case "enter":
 		if len(m.items) > 0 && m.cursor < len(m.items) {
-			selectedItem := m.items[m.cursor]
-			m.choice = selectedItem.path
-			if selectedItem.isSession {
-				m.action = "switch"
-			} else {
-				m.action = "create"
-			}
+			m.choose(m.items[m.cursor])
 			return m, tea.Quit
 		}
 
@@ -281,13 +626,7 @@ func (m model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 		num, _ := strconv.Atoi(keypress)
 		if num > 0 && num <= len(m.items) {
-			selectedItem := m.items[num-1]
-			m.choice = selectedItem.path
-			if selectedItem.isSession {
-				m.action = "switch"
-			} else {
-				m.action = "create"
-			}
+			m.choose(m.items[num-1])
 			return m, tea.Quit
 		}
 	}
@@ -302,23 +641,31 @@ func (m model) handleSearchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc", "ctrl+c":
 		m.appMode = ModeNormal
 		m.searchInput.Blur()
+		if m.viewMode == ViewSessions {
+			m.allItems = m.sessionList()
+		}
 		m.items = m.allItems
 		m.cursor = 0
 		return m, nil
 
 	case "enter":
 		if len(m.items) > 0 {
-			selectedItem := m.items[0]
-			m.choice = selectedItem.path
-			if selectedItem.isSession {
-				m.action = "switch"
-			} else {
-				m.action = "create"
-			}
+			m.choose(m.items[0])
 			return m, tea.Quit
 		}
 		return m, nil
 
+	case "ctrl+a":
+		if m.viewMode == ViewSessions && m.searchInput.Value() != "" {
+			m.selectAll(m.items)
+			m.appMode = ModeNormal
+			m.searchInput.Blur()
+			m.allItems = m.sessionList()
+			m.items = m.allItems
+			m.cursor = 0
+		}
+		return m, nil
+
 	case "down", "ctrl+j":
 		if m.cursor < len(m.items)-1 {
 			m.cursor++
@@ -347,25 +694,24 @@ func (m model) handleNewSessionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.appMode = ModeNormal
 		m.searchInput.Blur()
 		m.viewMode = ViewSessions
-		m.refreshItems()
-		return m, nil
+		return m, m.refreshItems()
 
 	case "enter":
 		searchTerm := strings.TrimSpace(m.searchInput.Value())
 		if searchTerm != "" {
-			if isGitHubURL(searchTerm) {
-				m.choice = searchTerm
-				m.action = "clone_and_create"
-				return m, tea.Quit
+			if fields := strings.Fields(searchTerm); isRepoURL(fields[0], m.config) {
+				url, name, opts, err := parseCloneRequest(fields)
+				if err != nil {
+					m.message = err.Error()
+					return m, nil
+				}
+				m.cloneInput = searchTerm
+				return m.startClone(url, name, opts)
 			} else if len(m.items) > 0 {
 				if m.cursor < len(m.items) {
-					selectedItem := m.items[m.cursor]
-					m.choice = selectedItem.path
-					m.action = "create"
+					m.choose(m.items[m.cursor])
 				} else {
-					selectedItem := m.items[0]
-					m.choice = selectedItem.path
-					m.action = "create"
+					m.choose(m.items[0])
 				}
 			} else {
 				m.choice = searchTerm
@@ -373,6 +719,35 @@ func (m model) handleNewSessionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			return m, tea.Quit
 		}
+		if m.cursor < len(m.items) && m.items[m.cursor].isWorktree {
+			m.choose(m.items[m.cursor])
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case "tab":
+		if m.cursor < len(m.items) {
+			m.toggleWorktrees(m.items[m.cursor])
+		}
+		return m, nil
+
+	case "ctrl+t":
+		if m.cursor < len(m.items) {
+			selectedItem := m.items[m.cursor]
+			repo := selectedItem.path
+			if selectedItem.isWorktree {
+				repo = selectedItem.parent
+			}
+			if !isGitRepo(repo) {
+				m.message = fmt.Sprintf("'%s' is not a git repository", selectedItem.title)
+				return m, nil
+			}
+			m.appMode = ModeNewWorktree
+			m.worktreeRepo = repo
+			m.searchInput.SetValue("")
+			m.searchInput.Placeholder = "Branch for the new worktree..."
+			return m, textinput.Blink
+		}
 		return m, nil
 
 	case "down", "ctrl+j":
@@ -395,6 +770,161 @@ func (m model) handleNewSessionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// startClone clones url in the background unless its target directory already
+// holds a different repository, in which case it asks for another directory
+// name.
+func (m model) startClone(url, name string, opts CloneOptions) (tea.Model, tea.Cmd) {
+	remote, err := parseRemoteURL(url, m.config.RemoteShorthands)
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+
+	target, err := cloneTarget(remote, m.config, name)
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+	if _, err := checkCloneTarget(target, remote); err != nil {
+		m.message = err.Error()
+		var conflict *cloneConflictError
+		if errors.As(err, &conflict) {
+			m.appMode = ModeCloneName
+			m.cloneURL = url
+			m.cloneOptions = opts
+			if name == "" {
+				name = suggestCloneName(remote)
+			}
+			m.searchInput.SetValue(name)
+			m.searchInput.Placeholder = "Directory name for the clone..."
+			return m, textinput.Blink
+		}
+		return m, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.appMode = ModeCloning
+	m.message = ""
+	m.cloneURL = url
+	m.cloneName = name
+	m.cloneOptions = opts
+	m.cloneDir = target
+	m.clone = cloneProgress{phase: "Connecting"}
+	m.cloneErr = nil
+	m.cloneCancel = cancel
+	m.cloneUpdates = make(chan tea.Msg, 16)
+	return m, runClone(ctx, url, m.config, name, opts, m.cloneUpdates)
+}
+
+// runClone clones in the background, sending cloneProgressMsgs and finally a
+// cloneFinishedMsg on updates. It returns the first of them.
+func runClone(ctx context.Context, url string, config Config, name string, opts CloneOptions, updates chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		go func() {
+			path, err := cloneRepoContext(ctx, url, config, name, opts, func(p cloneProgress) {
+				select {
+				case updates <- cloneProgressMsg(p):
+				default:
+				}
+			})
+			updates <- cloneFinishedMsg{path: path, err: err}
+		}()
+		return <-updates
+	}
+}
+
+func waitForClone(updates chan tea.Msg) tea.Cmd {
+	if updates == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// leaveClone returns to the new session prompt with the clone URL filled in.
+func (m *model) leaveClone() {
+	m.appMode = ModeNewSession
+	m.searchInput.SetValue(m.cloneInput)
+	m.searchInput.Placeholder = newSessionPlaceholder
+	m.cloneURL = ""
+	m.cloneErr = nil
+	m.message = ""
+}
+
+func (m model) handleCloningMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		if m.cloneCancel != nil {
+			m.cloneCancel()
+			m.cancelling = true
+			m.clone.phase = "Cancelling"
+			return m, nil
+		}
+		m.leaveClone()
+		return m, nil
+
+	case "enter":
+		if m.cloneErr != nil {
+			return m.startClone(m.cloneURL, m.cloneName, m.cloneOptions)
+		}
+	}
+	return m, nil
+}
+
+func (m model) handleCloneNameMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.leaveClone()
+		return m, nil
+
+	case "enter":
+		name := strings.TrimSpace(m.searchInput.Value())
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			m.message = "Enter a directory name without slashes"
+			return m, nil
+		}
+		return m.startClone(m.cloneURL, name, m.cloneOptions)
+	}
+
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) handleNewWorktreeMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.appMode = ModeNewSession
+		m.worktreeRepo = ""
+		m.searchInput.SetValue("")
+		m.searchInput.Placeholder = newSessionPlaceholder
+		m.filterItems("")
+		return m, nil
+
+	case "enter":
+		branch := strings.TrimSpace(m.searchInput.Value())
+		if branch == "" {
+			return m, nil
+		}
+		path, sessionName, err := addWorktree(m.worktreeRepo, branch)
+		if err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+		m.choice = path
+		m.sessionName = sessionName
+		m.action = "create_worktree"
+		return m, tea.Quit
+	}
+
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
 func (m model) handleRenameMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
@@ -408,24 +938,90 @@ func (m model) handleRenameMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		newName := strings.TrimSpace(m.searchInput.Value())
 		if newName != "" && newName != m.renameTarget {
-			err := renameTmuxSession(m.renameTarget, newName)
+			err := renameTmuxSession(m.tmux, m.renameTarget, newName)
 			if err != nil {
 				m.message = fmt.Sprintf("Error renaming session: %v", err)
 			} else {
+				renameInHistory(m.renameTarget, sanitizeSessionName(newName))
+				m.history.rename(sessionKey(m.renameTarget), sessionKey(sanitizeSessionName(newName)))
 				m.message = fmt.Sprintf("Session renamed to '%s'", newName)
-				m.refreshItems()
+				cmd = m.refreshItems()
 			}
 		}
 		m.appMode = ModeNormal
 		m.searchInput.Blur()
 		m.renameTarget = ""
-		return m, nil
+		return m, cmd
 	}
 
 	m.searchInput, cmd = m.searchInput.Update(msg)
 	return m, cmd
 }
 
+func (m *model) choose(selected item) {
+	m.choice = selected.path
+	m.sessionName = selected.sessionName
+	switch {
+	case selected.isSession, selected.isWindow, selected.isPane:
+		m.action = "switch"
+	case selected.isWorktree:
+		m.action = "create_worktree"
+	default:
+		m.action = "create"
+	}
+}
+
+// toggleWorktrees expands a repository in the project list into its
+// worktrees, or collapses it again.
+func (m *model) toggleWorktrees(selected item) {
+	repo := selected.path
+	if selected.isWorktree {
+		repo = selected.parent
+	}
+
+	if _, expanded := m.worktrees[repo]; expanded {
+		delete(m.worktrees, repo)
+	} else {
+		if !isGitRepo(repo) {
+			m.message = fmt.Sprintf("'%s' is not a git repository", selected.title)
+			return
+		}
+		items, err := worktreeItems(repo)
+		if err != nil {
+			m.message = fmt.Sprintf("Error listing worktrees: %v", err)
+			return
+		}
+		if m.worktrees == nil {
+			m.worktrees = map[string][]item{}
+		}
+		m.worktrees[repo] = items
+	}
+
+	m.allItems = m.projectList()
+	m.filterItems(m.searchInput.Value())
+	for i, it := range m.items {
+		if it.path == repo && !it.isWorktree {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// projectList is the project items with the worktrees of expanded
+// repositories listed right after them.
+func (m model) projectList() []item {
+	if len(m.worktrees) == 0 {
+		return m.projectItems
+	}
+
+	var items []item
+	for _, it := range m.projectItems {
+		items = append(items, it)
+		items = append(items, m.worktrees[it.path]...)
+	}
+	return items
+}
+
 type searchResult struct {
 	item  item
 	score int
@@ -438,7 +1034,6 @@ func (m *model) filterItems(query string) {
 		return
 	}
 
-	query = strings.ToLower(query)
 	var results []searchResult
 
 	for _, item := range m.allItems {
@@ -448,8 +1043,12 @@ func (m *model) filterItems(query string) {
 		}
 	}
 
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
+	now := time.Now()
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return m.history.frecency(results[i].item.historyKey(), now) > m.history.frecency(results[j].item.historyKey(), now)
 	})
 
 	var filtered []item
@@ -461,91 +1060,114 @@ func (m *model) filterItems(query string) {
 	m.cursor = 0
 }
 
-func calculateSearchScore(item item, query string) int {
-	title := strings.ToLower(item.title)
-	desc := strings.ToLower(item.desc)
-	query = strings.ToLower(strings.TrimSpace(query))
+// titleMatchBonus favours matches in an item's name over matches that only
+// land somewhere in its path.
+const titleMatchBonus = 50
 
-	if query == "" {
-		return 1
-	}
-
-	queryWords := strings.Fields(query)
+func calculateSearchScore(item item, query string) int {
+	queryWords := splitQuery(query)
 	if len(queryWords) == 0 {
 		return 0
 	}
 
 	score := 0
-	matchedWords := 0
-
 	for _, word := range queryWords {
-		wordScore := 0
-
-		if title == word {
-			wordScore += 1000
-		}
-
-		if strings.HasPrefix(title, word) {
-			wordScore += 500
-		}
-
-		if strings.Contains(title, word) {
-			wordScore += 200
-			matchedWords++
-		}
-
-		if strings.Contains(desc, word) {
-			wordScore += 100
-			matchedWords++
+		titleScore, _, titleOK := fuzzyMatch(item.title, word)
+		descScore, _, descOK := fuzzyMatch(item.desc, word)
+		if !titleOK && !descOK {
+			return 0
 		}
 
-		if wordScore == 0 {
-			titleWords := strings.FieldsFunc(title, func(c rune) bool {
-				return c == '-' || c == '_' || c == '.' || c == ' '
-			})
-			for _, titleWord := range titleWords {
-				if strings.Contains(titleWord, word) {
-					wordScore += 150
-					matchedWords++
-					break
-				}
+		wordScore := descScore
+		if titleOK && (!descOK || titleScore+titleMatchBonus > descScore) {
+			wordScore = titleScore + titleMatchBonus
+			if strings.EqualFold(item.title, word) {
+				wordScore += titleMatchBonus
 			}
 		}
-
 		score += wordScore
 	}
 
-	if matchedWords > 1 {
-		score += matchedWords * 100
+	score -= strings.Count(item.desc, "/")
+
+	if score < 1 {
+		score = 1
 	}
+	return score
+}
 
-	if matchedWords == len(queryWords) {
-		score += 300
+func (m *model) refreshItems() tea.Cmd {
+	var cmd tea.Cmd
+	if m.viewMode == ViewSessions {
+		m.sessions = getSessionItems(m.tmux)
+		m.history.sort(m.sessions)
+		m.allItems = m.sessionList()
+		m.gitStatuses = map[string]*gitStatus{}
+		m.loadingDetails = true
+		cmd = loadSessionDetails(m.tmux)
+	} else {
+		m.allItems = m.projectList()
+		m.scanning = true
+		cmd = scanProjects(m.config)
 	}
+	m.items = m.allItems
+	m.cursor = 0
+	return cmd
+}
 
-	pathDepth := strings.Count(item.desc, "/")
-	score += (10 - pathDepth) * 10
+// setProjectItems swaps in freshly scanned projects, keeping the current
+// filter and selection when the project list is on screen.
+func (m *model) setProjectItems(items []item) {
+	m.history.sort(items)
+	m.projectItems = items
+	if m.viewMode != ViewProjects {
+		return
+	}
 
-	if strings.Count(item.desc, "/") == 2 {
-		score += 200
+	var selected string
+	if m.cursor < len(m.items) {
+		selected = m.items[m.cursor].path
 	}
 
-	if matchedWords == 0 {
-		return 0
+	m.allItems = m.projectList()
+	if m.appMode == ModeSearch || m.appMode == ModeNewSession {
+		m.filterItems(m.searchInput.Value())
+	} else {
+		m.items = m.allItems
 	}
 
-	return score
+	m.cursor = 0
+	for i, item := range m.items {
+		if item.path == selected {
+			m.cursor = i
+			break
+		}
+	}
 }
 
-func (m *model) refreshItems() {
-	if m.viewMode == ViewSessions {
-		m.allItems = getSessionItems()
-	} else {
-		m.projectItems = getProjectItems(m.config)
-		m.allItems = m.projectItems
+// loadSessionDetails lists the windows of all sessions with a single
+// list-panes call.
+func loadSessionDetails(t Tmux) tea.Cmd {
+	return func() tea.Msg {
+		panes, err := t.ListPanes()
+		if err != nil {
+			return sessionDetailsMsg{}
+		}
+
+		bySession := map[string][]Pane{}
+		for _, pane := range panes {
+			bySession[pane.Session] = append(bySession[pane.Session], pane)
+		}
+		return sessionDetailsMsg{windows: windowsBySession(panes), panes: bySession}
+	}
+}
+
+func scanProjects(config Config) tea.Cmd {
+	return func() tea.Msg {
+		projects, cache := discoverProjects(config, loadProjectCache())
+		saveProjectCache(cache)
+		return projectsScannedMsg{items: buildProjectItems(projects)}
 	}
-	m.items = m.allItems
-	m.cursor = 0
 }
 
 func (m model) View() string {
@@ -555,15 +1177,28 @@ func (m model) View() string {
 	if m.quitting {
 		return ""
 	}
+	if m.appMode == ModeCloning {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.cloneView())
+	}
 
-	var title string
-	var titleWidth int
-	if m.appMode == ModeNewSession {
-		titleWidth = 110
-	} else {
-		titleWidth = 50
+	newSessionView := m.appMode == ModeNewSession || m.appMode == ModeNewWorktree || m.appMode == ModeCloneName
+
+	// Next to a project preview, the project list narrows to the width of
+	// the session list unless the terminal fits both at full width.
+	preview, hasPreview := m.previewPanel()
+	listStyle := sessionListStyle
+	pathWidth := listPathWidth
+	if newSessionView {
+		listStyle = sessionListStyleFull
+		if hasPreview && m.width < panelWidth(sessionListStyleFull)+lipgloss.Width(preview) {
+			listStyle = sessionListStyle
+			pathWidth = compactPathWidth
+		}
 	}
 
+	var title string
+	titleWidth := listStyle.GetWidth()
+
 	titleStyleDynamic := titleStyle.Copy().Width(titleWidth)
 
 	switch m.appMode {
@@ -573,6 +1208,12 @@ func (m model) View() string {
 		title = titleStyleDynamic.Render(" New Session")
 	case ModeRename:
 		title = titleStyleDynamic.Render(" Rename Session")
+	case ModeSnapshots:
+		title = titleStyleDynamic.Render(" Snapshots")
+	case ModeNewWorktree:
+		title = titleStyleDynamic.Render(" New Worktree: " + filepath.Base(m.worktreeRepo))
+	case ModeCloneName:
+		title = titleStyleDynamic.Render(" Clone As")
 	default:
 		title = titleStyleDynamic.Render(" Tmux Session Manager")
 	}
@@ -587,10 +1228,14 @@ func (m model) View() string {
 		searchLine = keybindStyle.Render("+ ") + m.searchInput.View()
 	} else if m.appMode == ModeRename {
 		searchLine = keybindStyle.Render(" ") + m.searchInput.View()
+	} else if m.appMode == ModeNewWorktree {
+		searchLine = keybindStyle.Render("⎇ ") + m.searchInput.View()
+	} else if m.appMode == ModeCloneName {
+		searchLine = keybindStyle.Render(" ") + m.searchInput.View()
 	}
 
 	maxItems := len(m.items)
-	if m.appMode == ModeSearch || m.appMode == ModeNewSession {
+	if m.appMode == ModeSearch || newSessionView {
 		maxItems = 15
 	}
 
@@ -619,7 +1264,13 @@ func (m model) View() string {
 		itemCount++
 
 		var itemLine string
-		if item.isSession {
+		if item.isWindow || item.isPane {
+			var query string
+			if m.appMode == ModeSearch {
+				query = m.searchInput.Value()
+			}
+			itemLine = item.treeLine(actualIndex+1, query)
+		} else if item.isSession {
 			var indicator string
 			if item.isAttached {
 				indicator = activeIndicatorStyle.Render("●")
@@ -629,14 +1280,18 @@ func (m model) View() string {
 
 			var sessionTitle string
 			if m.appMode == ModeSearch {
-				sessionTitle = highlightMultiWordMatches(item.title, m.searchInput.Value())
+				sessionTitle = highlightMultiWordMatches(item.title, m.searchInput.Value(), listTitleWidth)
 			} else {
-				sessionTitle = item.title
+				sessionTitle = truncateToWidth(item.title, listTitleWidth)
+			}
+
+			if m.selected[item.title] {
+				sessionTitle = markedStyle.Render("✔ ") + sessionTitle
 			}
 
 			itemLine = fmt.Sprintf("%d %s %s (%s)", actualIndex+1, indicator, sessionTitle, item.windowCount)
 		} else {
-			if m.appMode == ModeNewSession {
+			if newSessionView {
 				fullPath := item.desc
 				if fullPath == "" {
 					fullPath = item.path
@@ -645,14 +1300,27 @@ func (m model) View() string {
 					fullPath = strings.Replace(fullPath, os.Getenv("HOME"), "~", 1)
 				}
 
-				highlightedPath := highlightMatches(fullPath, m.searchInput.Value())
-				itemLine = fmt.Sprintf("%d %s", actualIndex+1, highlightedPath)
+				if item.isWorktree {
+					worktreeTitle := highlightMultiWordMatches(item.title, m.searchInput.Value(), listTitleWidth)
+					itemLine = fmt.Sprintf("%d   ⎇ %s %s", actualIndex+1, worktreeTitle, pathStyle.Render(truncateToWidth(fullPath, max(pathWidth-listTitleWidth, 10))))
+				} else {
+					highlightedPath := highlightMatches(fullPath, m.searchInput.Value(), pathWidth)
+					itemLine = fmt.Sprintf("%d %s", actualIndex+1, highlightedPath)
+				}
 			} else {
-				itemLine = fmt.Sprintf("%d %s", actualIndex+1, item.title)
+				title := truncateToWidth(item.title, listTitleWidth)
+				itemLine = fmt.Sprintf("%d %s", actualIndex+1, title)
 				if item.desc != "" {
-					itemLine += fmt.Sprintf(" %s", pathStyle.Render(item.desc))
+					descWidth := listTitleWidth - displayWidth(title)
+					if descWidth < 10 {
+						descWidth = 10
+					}
+					itemLine += fmt.Sprintf(" %s", pathStyle.Render(truncateToWidth(item.desc, descWidth)))
 				}
 			}
+			if item.kind != "" {
+				itemLine += " " + projectKindStyle.Render(item.kind)
+			}
 		}
 
 		if actualIndex == m.cursor {
@@ -664,7 +1332,7 @@ func (m model) View() string {
 		itemLines = append(itemLines, itemLine)
 	}
 	var statusLine string
-	if m.appMode == ModeSearch || m.appMode == ModeNewSession {
+	if m.appMode == ModeSearch || newSessionView {
 		totalItems := len(m.items)
 		if totalItems > maxItems {
 			statusLine = keybindStyle.Render(fmt.Sprintf("  %d/%d", len(displayedItems), totalItems))
@@ -676,16 +1344,21 @@ func (m model) View() string {
 	if itemCount == 0 {
 		if m.appMode == ModeNewSession {
 			if m.searchInput.Value() != "" {
-				if isGitHubURL(m.searchInput.Value()) {
-					repoName := extractRepoName(m.searchInput.Value())
-					if repoName != "" {
-						itemLines = append(itemLines, selectedSessionStyle.Render(fmt.Sprintf("▶ Clone & create session: %s", repoName)))
+				if fields := strings.Fields(m.searchInput.Value()); len(fields) > 0 && isRepoURL(fields[0], m.config) {
+					url, _, opts, err := parseCloneRequest(fields)
+					if err != nil {
+						itemLines = append(itemLines, errorStyle.Render(err.Error()))
 					} else {
-						itemLines = append(itemLines, selectedSessionStyle.Render("▶ Clone repository"))
+						itemLines = append(itemLines, selectedSessionStyle.Render(fmt.Sprintf("▶ Clone & create session: %s", extractRepoName(url, m.config))))
+						if args := m.cloneArgs(url, opts); len(args) > 0 {
+							itemLines = append(itemLines, pathStyle.Render("  git clone "+strings.Join(args, " ")))
+						}
 					}
 				} else {
 					itemLines = append(itemLines, selectedSessionStyle.Render(fmt.Sprintf("▶ Create session: %s", m.searchInput.Value())))
 				}
+			} else if m.scanning {
+				itemLines = append(itemLines, inactiveIndicatorStyle.Render("Scanning projects..."))
 			} else {
 				itemLines = append(itemLines, inactiveIndicatorStyle.Render("No projects found"))
 			}
@@ -695,6 +1368,9 @@ func (m model) View() string {
 			} else {
 				itemLines = append(itemLines, inactiveIndicatorStyle.Render("Start typing to search..."))
 			}
+		} else if m.appMode == ModeSnapshots {
+			itemLines = append(itemLines, inactiveIndicatorStyle.Render("No snapshots saved"))
+			itemLines = append(itemLines, keybindStyle.Render("Press 's' to save one now"))
 		} else {
 			itemLines = append(itemLines, inactiveIndicatorStyle.Render("No tmux sessions found"))
 			itemLines = append(itemLines, keybindStyle.Render("Press 'n' to create a new session"))
@@ -703,20 +1379,55 @@ func (m model) View() string {
 
 	var keybinds []string
 	switch m.appMode {
-	case ModeSearch, ModeNewSession, ModeRename:
+	case ModeNewSession:
+		keybinds = []string{
+			formatKeybind("Enter", "select"),
+			formatKeybind(" ↑/↓ ", "navigate"),
+			formatKeybind(" Tab ", "worktrees"),
+			formatKeybind(" ^t  ", "new worktree"),
+			formatKeybind(" Esc ", "cancel"),
+		}
+	case ModeConfirm:
+		keybinds = []string{
+			formatKeybind(" y ", "confirm"),
+			formatKeybind(" n ", "cancel"),
+		}
+	case ModeSnapshots:
+		keybinds = []string{
+			formatKeybind("Enter", "restore"),
+			formatKeybind("  s  ", "save now"),
+			formatKeybind(" Esc ", "back"),
+		}
+	case ModeSearch, ModeRename, ModeNewWorktree, ModeCloneName:
 		keybinds = []string{
 			formatKeybind("Enter", "select"),
 			formatKeybind(" ↑/↓ ", "navigate"),
 			formatKeybind(" Esc ", "cancel"),
 		}
 	default:
+		if selected := len(m.selectedSessions()); selected > 0 {
+			keybinds = []string{
+				markedStyle.Render(fmt.Sprintf("%d selected", selected)),
+				formatKeybind("Spc", "toggle"),
+				formatKeybind(" a ", "all"),
+				formatKeybind(" d ", "kill"),
+				formatKeybind(" x ", "detach"),
+				formatKeybind(" w ", "snapshot"),
+				formatKeybind("Esc", "clear"),
+			}
+			break
+		}
 		keybinds = []string{
 			formatKeybind("j/k", "navigate"),
 			formatKeybind("1-9", "switch"),
+			formatKeybind("Tab", "windows"),
 			formatKeybind(" d ", "kill"),
+			formatKeybind("Spc", "select"),
 			formatKeybind(" r ", "rename"),
 			formatKeybind(" n ", "new"),
 			formatKeybind(" i ", "search"),
+			formatKeybind(" v ", "preview"),
+			formatKeybind(" S ", "snapshots"),
 			formatKeybind(" R ", "refresh"),
 			formatKeybind(" q ", "quit"),
 		}
@@ -733,23 +1444,31 @@ func (m model) View() string {
 		leftContent = append(leftContent, statusLine)
 	}
 	leftContent = append(leftContent, "")
+	if m.message != "" {
+		leftContent = append(leftContent, messageStyle.Render(m.message), "")
+	}
 	leftContent = append(leftContent, keybinds...)
 
-	var leftPanel string
-	if m.appMode == ModeNewSession {
-		leftPanel = sessionListStyleFull.Render(strings.Join(leftContent, "\n"))
-	} else {
-		leftPanel = sessionListStyle.Render(strings.Join(leftContent, "\n"))
-	}
+	leftPanel := listStyle.Render(strings.Join(leftContent, "\n"))
 
 	var content string
-	if m.appMode == ModeNewSession {
+	if newSessionView {
 		content = leftPanel
+		if hasPreview && m.width >= lipgloss.Width(leftPanel)+lipgloss.Width(preview) {
+			content = lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, preview)
+		}
 	} else {
 		var rightPanel string
-		if m.appMode == ModeNormal && len(m.items) > 0 && m.cursor < len(m.items) && m.items[m.cursor].isSession {
-			selectedSession := m.items[m.cursor]
-			rightPanel = buildSessionDetails(selectedSession.title)
+		if selectedSession, ok := m.sessionItem(m.selectedSessionName()); ok {
+			if m.panePreview {
+				rightPanel = buildPanePreview(selectedSession, m.paneCapture)
+			} else {
+				rightPanel = buildSessionDetails(selectedSession, m.sessionWindows[selectedSession.title], m.loadingDetails, m.gitStatuses)
+			}
+		} else if m.appMode == ModeConfirm {
+			rightPanel = m.buildConfirmDialog()
+		} else if m.appMode == ModeSnapshots && m.cursor < len(m.snapshots) {
+			rightPanel = buildSnapshotDetails(m.snapshots[m.cursor])
 		} else if m.appMode == ModeRename {
 			rightPanel = detailPanelStyle.Render("Renaming session...\n\nEnter new name for session")
 		} else if m.appMode == ModeSearch {
@@ -764,117 +1483,137 @@ func (m model) View() string {
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
 
-func isGitHubURL(input string) bool {
-	input = strings.TrimSpace(input)
-	return strings.HasPrefix(input, "https://github.com/") ||
-		strings.HasPrefix(input, "git@github.com:")
+// panelWidth is the width a panel rendered with style takes up, borders
+// included.
+func panelWidth(style lipgloss.Style) int {
+	return style.GetWidth() + style.GetHorizontalBorderSize()
 }
 
-func extractRepoName(url string) string {
-	url = strings.TrimSpace(url)
-
-	if strings.HasPrefix(url, "https://github.com/") {
-		path := strings.TrimPrefix(url, "https://github.com/")
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.Split(path, "/")
-		if len(parts) >= 2 {
-			return parts[1]
-		}
-	} else if strings.HasPrefix(url, "git@github.com:") {
-		path := strings.TrimPrefix(url, "git@github.com:")
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.Split(path, "/")
-		if len(parts) >= 2 {
-			return parts[1]
-		}
+// previewPanel renders the preview of the highlighted project in the New
+// Session view, if there is one.
+func (m model) previewPanel() (string, bool) {
+	if m.appMode != ModeNewSession || m.cursor >= len(m.items) {
+		return "", false
 	}
-
-	return ""
+	selected := m.items[m.cursor]
+	if selected.isSession || selected.path == "" {
+		return "", false
+	}
+	return buildProjectPreview(selected, m.previews[selected.path]), true
 }
 
-func cloneGitHubRepo(url string, config Config) (string, error) {
-	repoName := extractRepoName(url)
-	if repoName == "" {
-		return "", fmt.Errorf("could not extract repository name from URL")
+func (m model) cloneView() string {
+	title := titleStyle.Copy().Width(110).Render(" Cloning " + extractRepoName(m.cloneURL, m.config))
+
+	dir := strings.Replace(m.cloneDir, os.Getenv("HOME"), "~", 1)
+	lines := []string{
+		title,
+		"",
+		detailTextStyle.Render(truncateToWidth(m.cloneURL, listPathWidth)),
+		pathStyle.Render("→ " + truncateToWidth(dir, listPathWidth)),
 	}
+	if args := m.cloneArgs(m.cloneURL, m.cloneOptions); len(args) > 0 {
+		lines = append(lines, pathStyle.Render("  "+strings.Join(args, " ")))
+	}
+	lines = append(lines, "")
 
-	reposDir := config.ReposPath
-	if err := os.MkdirAll(reposDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create repos directory: %v", err)
+	var keybinds []string
+	if m.cloneErr != nil {
+		lines = append(lines, errorStyle.Render("Clone failed"), "")
+		lines = append(lines, lipgloss.NewStyle().Width(listPathWidth).Render(detailTextStyle.Render(m.cloneErr.Error())))
+		keybinds = []string{
+			formatKeybind("Enter", "retry"),
+			formatKeybind(" Esc ", "back"),
+		}
+	} else {
+		lines = append(lines,
+			detailTextStyle.Render(fmt.Sprintf("%s  %3d%%", m.clone.phase, m.clone.percent)),
+			renderProgressBar(m.clone.percent, listPathWidth),
+		)
+		keybinds = []string{
+			formatKeybind(" Esc ", "cancel"),
+		}
 	}
 
-	targetDir := filepath.Join(reposDir, repoName)
+	lines = append(lines, "")
+	lines = append(lines, keybinds...)
+	return sessionListStyleFull.Render(strings.Join(lines, "\n"))
+}
 
-	if _, err := os.Stat(targetDir); err == nil {
-		return targetDir, nil
+// cloneArgs lists the git clone options a clone of url would use, including
+// the configured defaults for its host.
+func (m model) cloneArgs(url string, opts CloneOptions) []string {
+	if remote, err := parseRemoteURL(url, m.config.RemoteShorthands); err == nil {
+		opts = m.config.cloneDefaults(remote.Host).merge(opts)
 	}
 
-	cmd := exec.Command("git", "clone", url, targetDir)
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to clone repository: %v", err)
+	args := opts.args()
+	if len(opts.Sparse) > 0 {
+		args[len(args)-1] = "--sparse " + strings.Join(opts.Sparse, ",")
 	}
-
-	return targetDir, nil
+	return args
 }
 
-func buildSessionDetails(sessionName string) string {
-	header := detailHeaderStyle.Render(" " + sessionName)
+func renderProgressBar(percent, width int) string {
+	percent = max(0, min(percent, 100))
+	filled := width * percent / 100
+	return progressFilledStyle.Render(strings.Repeat("█", filled)) +
+		progressEmptyStyle.Render(strings.Repeat("░", width-filled))
+}
 
-	statusCmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}:#{session_attached}:#{session_windows}", "-f", "#{==:#{session_name},"+sessionName+"}")
-	statusOutput, err := statusCmd.Output()
+// sessionSummary is the header of the detail panel: the session's name,
+// whether it is attached and how many windows it has.
+func sessionSummary(session item) []string {
+	header := detailHeaderStyle.Render(" " + session.title)
 
-	var status, windowCount string
-	if err == nil && len(statusOutput) > 0 {
-		parts := strings.Split(strings.TrimSpace(string(statusOutput)), ":")
-		if len(parts) >= 3 {
-			if parts[1] == "1" {
-				status = activeIndicatorStyle.Render("⚡ Active")
-			} else {
-				status = inactiveIndicatorStyle.Render("○ Inactive")
-			}
-			windowCount = parts[2]
-		}
+	var status string
+	if session.isAttached {
+		status = activeIndicatorStyle.Render("⚡ Active")
+	} else {
+		status = inactiveIndicatorStyle.Render("○ Inactive")
 	}
 
 	statusLine := fmt.Sprintf("Status: %s", status)
-	windowsLine := fmt.Sprintf("Windows: %s", windowCount)
+	windowsLine := fmt.Sprintf("Windows: %s", session.windowCount)
+
+	return []string{
+		header,
+		"",
+		detailTextStyle.Render(statusLine),
+		detailTextStyle.Render(windowsLine),
+		"",
+	}
+}
 
-	windowsCmd := exec.Command("tmux", "list-windows", "-t", sessionName, "-F", "#{window_index}: #{window_name}")
-	windowsOutput, err := windowsCmd.Output()
+func buildSessionDetails(session item, windows []Window, loading bool, gitStatuses map[string]*gitStatus) string {
 
 	var windowDetails []string
 	windowDetails = append(windowDetails, windowHeaderStyle.Render("⊞ Windows"))
-	if err == nil && len(windowsOutput) > 0 {
-		windows := strings.Split(strings.TrimSpace(string(windowsOutput)), "\n")
+	if loading && len(windows) == 0 {
+		windowDetails = append(windowDetails, windowStyle.Render(inactiveIndicatorStyle.Render("Loading...")))
+	} else if len(windows) > 0 {
 		for _, window := range windows {
-			if window != "" {
-				parts := strings.SplitN(window, ": ", 2)
-				if len(parts) == 2 {
-					windowNum := parts[0]
-					windowName := parts[1]
-
-					dirCmd := exec.Command("tmux", "display-message", "-t", sessionName+":"+windowNum, "-p", "#{pane_current_path}")
-					dirOutput, dirErr := dirCmd.Output()
-
-					cmdCmd := exec.Command("tmux", "display-message", "-t", sessionName+":"+windowNum, "-p", "#{pane_current_command}")
-					cmdOutput, cmdErr := cmdCmd.Output()
-
-					windowLine := fmt.Sprintf("%s: %s", windowNum, windowName)
-					windowDetails = append(windowDetails, windowStyle.Render(windowLine))
+			windowLine := fmt.Sprintf("%s: %s", window.Index, window.Name)
+			windowDetails = append(windowDetails, windowStyle.Render(windowLine))
 
-					if cmdErr == nil {
-						currentCmd := strings.TrimSpace(string(cmdOutput))
-						if currentCmd != "" && currentCmd != "bash" && currentCmd != "zsh" && currentCmd != "fish" {
-							windowDetails = append(windowDetails, windowStyle.Render("     "+programStyle.Render(currentCmd)))
-						}
-					}
+			currentCmd := window.Command
+			if !isShell(currentCmd) {
+				windowDetails = append(windowDetails, windowStyle.Render("     "+programStyle.Render(currentCmd)))
+			}
 
-					if dirErr == nil {
-						currentDir := strings.TrimSpace(string(dirOutput))
-						if strings.HasPrefix(currentDir, os.Getenv("HOME")) {
-							currentDir = strings.Replace(currentDir, os.Getenv("HOME"), "~", 1)
-						}
-						windowDetails = append(windowDetails, windowStyle.Render("    \uea83 "+fileTreeStyle.Render(currentDir)))
+			currentDir := window.Path
+			if strings.HasPrefix(currentDir, os.Getenv("HOME")) {
+				currentDir = strings.Replace(currentDir, os.Getenv("HOME"), "~", 1)
+			}
+			windowDetails = append(windowDetails, windowStyle.Render("    \uea83 "+fileTreeStyle.Render(currentDir)))
+
+			if status, loaded := gitStatuses[window.Path]; loaded {
+				if status == nil {
+					windowDetails = append(windowDetails, windowStyle.Render("    "+inactiveIndicatorStyle.Render("⎇ …")))
+				} else if status.ok {
+					windowDetails = append(windowDetails, windowStyle.Render("    "+status.render()))
+					if status.subject != "" {
+						windowDetails = append(windowDetails, windowStyle.Render("      "+pathStyle.Render(truncateToWidth(status.subject, 48))))
 					}
 				}
 			}
@@ -883,49 +1622,28 @@ func buildSessionDetails(sessionName string) string {
 		windowDetails = append(windowDetails, windowStyle.Render("No windows found"))
 	}
 
-	content := []string{
-		header,
-		"",
-		detailTextStyle.Render(statusLine),
-		detailTextStyle.Render(windowsLine),
-		"",
-	}
+	content := sessionSummary(session)
 	content = append(content, windowDetails...)
 
 	return detailPanelStyle.Render(strings.Join(content, "\n"))
 }
 
-func getSessionItems() []item {
+func getSessionItems(t Tmux) []item {
 	var items []item
 
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}:#{session_attached}:#{session_windows}")
-	output, err := cmd.Output()
+	sessions, err := t.ListSessions()
 	if err != nil {
 		return items
 	}
 
-	sessions := strings.Split(strings.TrimSpace(string(output)), "\n")
-
 	for _, session := range sessions {
-		if session == "" {
-			continue
-		}
-
-		parts := strings.Split(session, ":")
-		if len(parts) < 3 {
-			continue
-		}
-
-		name := parts[0]
-		attached := parts[1] == "1"
-		windows := parts[2]
-
 		items = append(items, item{
-			title:       name,
-			path:        name,
+			title:       session.Name,
+			path:        session.Name,
 			isSession:   true,
-			isAttached:  attached,
-			windowCount: windows,
+			isAttached:  session.Attached,
+			windowCount: strconv.Itoa(session.Windows),
+			sessionName: session.Name,
 		})
 	}
 
@@ -936,58 +1654,19 @@ func getSessionItems() []item {
 	return items
 }
 
-func getProjectItems(config Config) []item {
+func buildProjectItems(projects []project) []item {
 	var items []item
 
-	var existingPaths []string
-	for _, path := range config.ProjectPaths {
-		if _, err := os.Stat(path); err == nil {
-			existingPaths = append(existingPaths, path)
-		}
-	}
-
-	if len(existingPaths) == 0 {
-		return items
-	}
-
-	args := []string{}
-	args = append(args, existingPaths...)
-	args = append(args, "-mindepth", "1", "-maxdepth", "3", "-type", "d")
-
-	cmd := exec.Command("find", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil && stdout.Len() == 0 {
-		return items
-	}
-
-	output := stdout.Bytes()
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		baseName := filepath.Base(line)
-		if strings.HasPrefix(baseName, ".") ||
-			baseName == "node_modules" ||
-			baseName == "target" ||
-			baseName == "build" ||
-			baseName == "dist" {
-			continue
-		}
-
-		name := baseName
-		desc := strings.Replace(line, os.Getenv("HOME"), "~", 1)
+	for _, project := range projects {
+		name := filepath.Base(project.path)
+		desc := strings.Replace(project.path, os.Getenv("HOME"), "~", 1)
 
 		items = append(items, item{
 			title:     name,
 			desc:      desc,
-			path:      line,
+			path:      project.path,
 			isSession: false,
+			kind:      project.kind,
 		})
 	}
 
@@ -998,236 +1677,196 @@ func getProjectItems(config Config) []item {
 	return items
 }
 
-func createTmuxSession(selectedPath string) error {
+func sessionNameForPath(path string) string {
+	return strings.ReplaceAll(filepath.Base(path), ".", "_")
+}
+
+func sanitizeSessionName(name string) string {
+	name = strings.ReplaceAll(name, ".", "_")
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+func createTmuxSession(t Tmux, config Config, selectedPath string) error {
 	if selectedPath == "" {
 		return nil
 	}
 
-	selectedName := strings.ReplaceAll(filepath.Base(selectedPath), ".", "_")
-
-	tmuxRunning := exec.Command("pgrep", "tmux")
-	tmuxRunning.Run()
-	tmuxIsRunning := tmuxRunning.ProcessState.Success()
-
-	_, insideTmux := os.LookupEnv("TMUX")
-
-	if !insideTmux && !tmuxIsRunning {
-		cmd := exec.Command("tmux", "new-session", "-s", selectedName, "-c", selectedPath)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			exec.Command("tmux", "send-keys", "-t", selectedName, "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"", "Enter").Run()
-		}()
+	return createProjectSession(t, config, sessionNameForPath(selectedPath), selectedPath)
+}
 
-		return cmd.Run()
+func createProjectSession(t Tmux, config Config, selectedName, selectedPath string) error {
+	layout, hasLayout, err := findLayout(config, selectedPath)
+	if err != nil {
+		return err
+	}
+	if hasLayout {
+		return startTmuxSession(t, selectedName, layout.firstDir(selectedPath), func() error {
+			return applyLayout(t, selectedName, selectedPath, layout)
+		})
 	}
 
-	checkSession := exec.Command("tmux", "has-session", "-t="+selectedName)
-	err := checkSession.Run()
-	if err != nil {
-		createCmd := exec.Command("tmux", "new-session", "-d", "-s", selectedName, "-c", selectedPath)
-		if err := createCmd.Run(); err != nil {
-			return fmt.Errorf("failed to create session: %v", err)
-		}
+	return startTmuxSession(t, selectedName, selectedPath, func() error {
+		return sendStartupCommand(t, selectedName, config.startupCommand(selectedPath))
+	})
+}
 
-		nvimCmd := exec.Command("tmux", "send-keys", "-t", selectedName, "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"", "Enter")
-		nvimCmd.Run()
+func createNamedTmuxSession(t Tmux, config Config, sessionName string) error {
+	if sessionName == "" {
+		return nil
 	}
 
-	switchCmd := exec.Command("tmux", "switch-client", "-t", selectedName)
-	return switchCmd.Run()
+	sessionName = sanitizeSessionName(sessionName)
+	return startTmuxSession(t, sessionName, "", func() error {
+		return sendStartupCommand(t, sessionName, config.EditorCmd)
+	})
 }
 
-func createNamedTmuxSession(sessionName string) error {
-	if sessionName == "" {
+func sendStartupCommand(t Tmux, target, command string) error {
+	if strings.TrimSpace(command) == "" {
 		return nil
 	}
 
-	sessionName = strings.ReplaceAll(sessionName, ".", "_")
-	sessionName = strings.ReplaceAll(sessionName, " ", "_")
-	tmuxRunning := exec.Command("pgrep", "tmux")
-	tmuxRunning.Run()
-	tmuxIsRunning := tmuxRunning.ProcessState.Success()
-	_, insideTmux := os.LookupEnv("TMUX")
+	return t.SendKeys(target, command, "Enter")
+}
 
-	if !insideTmux && !tmuxIsRunning {
-		cmd := exec.Command("tmux", "new-session", "-s", sessionName)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+func startTmuxSession(t Tmux, sessionName, dir string, setup func() error) error {
+	_, insideTmux := os.LookupEnv("TMUX")
 
+	if !insideTmux && !tmuxServerRunning(t) {
 		go func() {
 			time.Sleep(100 * time.Millisecond)
-			exec.Command("tmux", "send-keys", "-t", sessionName, "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"", "Enter").Run()
+			setup()
 		}()
 
-		return cmd.Run()
+		return t.NewSession(sessionName, dir, true)
 	}
 
-	checkSession := exec.Command("tmux", "has-session", "-t="+sessionName)
-	err := checkSession.Run()
-	if err != nil {
-		createCmd := exec.Command("tmux", "new-session", "-d", "-s", sessionName)
-		if err := createCmd.Run(); err != nil {
+	if !t.HasSession(sessionName) {
+		if err := t.NewSession(sessionName, dir, false); err != nil {
 			return fmt.Errorf("failed to create session: %v", err)
 		}
-		nvimCmd := exec.Command("tmux", "send-keys", "-t", sessionName, "nvim -c \"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end\"", "Enter")
-		nvimCmd.Run()
+
+		if err := setup(); err != nil {
+			return fmt.Errorf("failed to set up session: %v", err)
+		}
 	}
-	switchCmd := exec.Command("tmux", "switch-client", "-t", sessionName)
-	return switchCmd.Run()
+
+	return switchTmuxSession(t, sessionName)
 }
 
-func switchTmuxSession(sessionName string) error {
+// switchTmuxSession switches the current client to the session, or attaches
+// to it when not running inside tmux.
+func switchTmuxSession(t Tmux, sessionName string) error {
 	if sessionName == "" {
 		return nil
 	}
 
-	switchCmd := exec.Command("tmux", "switch-client", "-t", sessionName)
-	return switchCmd.Run()
+	if _, insideTmux := os.LookupEnv("TMUX"); !insideTmux {
+		return t.Attach(sessionName)
+	}
+	return t.SwitchClient(sessionName)
 }
 
-func killTmuxSession(sessionName string) error {
+func sessionExists(t Tmux, sessionName string) bool {
+	if sessionName == "" {
+		return false
+	}
+
+	return t.HasSession(sessionName)
+}
+
+func killTmuxSession(t Tmux, sessionName string) error {
 	if sessionName == "" {
 		return nil
 	}
 
-	killCmd := exec.Command("tmux", "kill-session", "-t", sessionName)
-	return killCmd.Run()
+	return t.Kill(sessionName)
 }
 
-func renameTmuxSession(oldName, newName string) error {
+func renameTmuxSession(t Tmux, oldName, newName string) error {
 	if oldName == "" || newName == "" {
 		return fmt.Errorf("session names cannot be empty")
 	}
-	newName = strings.ReplaceAll(newName, ".", "_")
-	newName = strings.ReplaceAll(newName, " ", "_")
+	newName = sanitizeSessionName(newName)
 
-	renameCmd := exec.Command("tmux", "rename-session", "-t", oldName, newName)
-	return renameCmd.Run()
+	return t.Rename(oldName, newName)
 }
 
-func highlightMatches(text, query string) string {
+func highlightMatches(text, query string, maxWidth int) string {
 	if query == "" {
-		return pathStyle.Render(text)
-	}
-
-	query = strings.ToLower(query)
-	textLower := strings.ToLower(text)
-
-	var result strings.Builder
-	i := 0
-
-	for i < len(text) {
-		if i < len(textLower) && strings.HasPrefix(textLower[i:], query) {
-			matchedPart := text[i : i+len(query)]
-			result.WriteString(highlightStyle.Render(matchedPart))
-			i += len(query)
-		} else {
-			result.WriteString(pathStyle.Render(string(text[i])))
-			i++
-		}
+		return pathStyle.Render(truncateToWidth(text, maxWidth))
 	}
 
-	return result.String()
+	return renderHighlights(text, fuzzyPositions(text, query), &pathStyle, maxWidth)
 }
 
-func highlightMultiWordMatches(text, query string) string {
-	if query == "" {
-		return text
-	}
-
-	query = strings.ToLower(strings.TrimSpace(query))
-	queryWords := strings.Fields(query)
-	if len(queryWords) == 0 {
-		return text
+func highlightMultiWordMatches(text, query string, maxWidth int) string {
+	if strings.TrimSpace(query) == "" {
+		return truncateToWidth(text, maxWidth)
 	}
 
-	textLower := strings.ToLower(text)
+	return renderHighlights(text, fuzzyPositions(text, query), nil, maxWidth)
+}
 
-	highlights := make([]bool, len(text))
+func formatKeybind(key, action string) string {
+	return keyStyle.Render(key) + separatorStyle.Render(" │ ") + actionStyle.Render(action)
+}
 
-	for _, word := range queryWords {
-		startIdx := 0
-		for {
-			idx := strings.Index(textLower[startIdx:], word)
-			if idx == -1 {
-				break
-			}
+func main() {
+	config := LoadConfig()
+	tmux := execTmux{}
 
-			actualIdx := startIdx + idx
-			for i := actualIdx; i < actualIdx+len(word) && i < len(highlights); i++ {
-				highlights[i] = true
-			}
-			startIdx = actualIdx + 1
-		}
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], config, tmux, os.Stdout, os.Stderr))
+	}
 
-		textWords := strings.FieldsFunc(textLower, func(c rune) bool {
-			return c == '-' || c == '_' || c == '.' || c == ' '
-		})
+	m := newModel(config, tmux)
 
-		currentPos := 0
-		for _, textWord := range textWords {
-			wordStart := strings.Index(textLower[currentPos:], textWord)
-			if wordStart != -1 {
-				wordStart += currentPos
-				if strings.Contains(textWord, word) {
-					subIdx := strings.Index(textWord, word)
-					if subIdx != -1 {
-						actualStart := wordStart + subIdx
-						for i := actualStart; i < actualStart+len(word) && i < len(highlights); i++ {
-							highlights[i] = true
-						}
-					}
-				}
-				currentPos = wordStart + len(textWord)
-			}
-		}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Printf("Error: %v", err)
+		os.Exit(1)
 	}
 
-	var resultBuilder strings.Builder
-	i := 0
-	for i < len(text) {
-		if highlights[i] {
-			j := i
-			for j < len(text) && highlights[j] {
-				j++
-			}
-			resultBuilder.WriteString(highlightStyle.Render(text[i:j]))
-			i = j
-		} else {
-			resultBuilder.WriteString(string(text[i]))
-			i++
+	if m, ok := finalModel.(model); ok && m.choice != "" {
+		if err := m.runChoice(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 	}
-
-	return resultBuilder.String()
 }
 
-func formatKeybind(key, action string) string {
-	return keyStyle.Render(key) + separatorStyle.Render(" │ ") + actionStyle.Render(action)
-}
-
-func main() {
-	config := LoadConfig()
-
+func newModel(config Config, tmux Tmux) model {
 	ti := textinput.New()
 	ti.Placeholder = "Type to search..."
-	ti.CharLimit = 50
 	ti.Width = 40
 
 	m := model{
-		appMode:      ModeNormal,
-		viewMode:     ViewSessions,
-		searchInput:  ti,
-		config:       config,
-		projectItems: getProjectItems(config),
-	}
-
-	sessionItems := getSessionItems()
+		appMode:        ModeNormal,
+		viewMode:       ViewSessions,
+		searchInput:    ti,
+		config:         config,
+		tmux:           tmux,
+		projectItems:   buildProjectItems(cachedProjects(config, loadProjectCache())),
+		scanning:       true,
+		history:        loadHistory(),
+		gitStatuses:    map[string]*gitStatus{},
+		loadingDetails: true,
+		panePreview:    config.PanePreview,
+		expanded:       map[string]bool{},
+		selected:       map[string]bool{},
+		previews:       map[string]*projectPreview{},
+	}
+	m.history.sort(m.projectItems)
+
+	if problems := config.Validate(); len(problems) > 0 {
+		m.message = "Config: " + strings.Join(problems, "; ")
+	}
+
+	sessionItems := getSessionItems(tmux)
+	m.history.sort(sessionItems)
+	m.sessions = sessionItems
 	if len(sessionItems) > 0 {
 		m.allItems = sessionItems
 		m.items = sessionItems
@@ -1237,47 +1876,32 @@ func main() {
 		m.items = m.allItems
 	}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Printf("Error: %v", err)
-		os.Exit(1)
-	}
-
-	if m, ok := finalModel.(model); ok && m.choice != "" {
-		switch m.action {
-		case "create":
-			err := createTmuxSession(m.choice)
-			if err != nil {
-				fmt.Printf("Error creating tmux session: %v\n", err)
-				os.Exit(1)
-			}
-		case "create_named":
-			err := createNamedTmuxSession(m.choice)
-			if err != nil {
-				fmt.Printf("Error creating tmux session: %v\n", err)
-				os.Exit(1)
-			}
-		case "clone_and_create":
-			fmt.Printf("Cloning repository: %s\n", m.choice)
-			clonedPath, err := cloneGitHubRepo(m.choice, m.config)
-			if err != nil {
-				fmt.Printf("Error cloning repository: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Printf("Repository cloned to: %s\n", clonedPath)
+	return m
+}
 
-			err = createTmuxSession(clonedPath)
-			if err != nil {
-				fmt.Printf("Error creating tmux session: %v\n", err)
-				os.Exit(1)
-			}
-		case "switch":
-			err := switchTmuxSession(m.choice)
-			if err != nil {
-				fmt.Printf("Error switching to tmux session: %v\n", err)
-				os.Exit(1)
-			}
+// runChoice carries out what was chosen in the TUI once it has exited.
+func (m model) runChoice() error {
+	switch m.action {
+	case "create":
+		if err := createTmuxSession(m.tmux, m.config, m.choice); err != nil {
+			return fmt.Errorf("Error creating tmux session: %v", err)
+		}
+		recordUse(projectKey(m.choice), sessionKey(sessionNameForPath(m.choice)))
+	case "create_worktree":
+		if err := createProjectSession(m.tmux, m.config, m.sessionName, m.choice); err != nil {
+			return fmt.Errorf("Error creating tmux session: %v", err)
+		}
+		recordUse(projectKey(m.choice), sessionKey(m.sessionName))
+	case "create_named":
+		if err := createNamedTmuxSession(m.tmux, m.config, m.choice); err != nil {
+			return fmt.Errorf("Error creating tmux session: %v", err)
+		}
+		recordUse(sessionKey(sanitizeSessionName(m.choice)))
+	case "switch":
+		if err := switchTmuxSession(m.tmux, m.choice); err != nil {
+			return fmt.Errorf("Error switching to tmux session: %v", err)
 		}
+		recordUse(sessionKey(m.sessionName))
 	}
+	return nil
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestModel(t *testing.T, f *fakeTmux) model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("TMUX", "/tmp/tmux-test,1,0")
+	return newModel(DefaultConfig(), f)
+}
+
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "backspace":
+		return tea.KeyMsg{Type: tea.KeyBackspace}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// press sends each key to the model in turn, ignoring the commands Update
+// returns.
+func press(m model, keys ...string) model {
+	for _, key := range keys {
+		next, _ := m.Update(keyMsg(key))
+		m = next.(model)
+	}
+	return m
+}
+
+func typeText(m model, text string) model {
+	for _, r := range text {
+		m = press(m, string(r))
+	}
+	return m
+}
+
+func (f *fakeTmux) has(name string) bool {
+	_, ok := f.sessions[name]
+	return ok
+}
+
+func TestKillAsksForConfirmation(t *testing.T) {
+	f := newFakeTmux("alpha", "beta")
+	m := newTestModel(t, f)
+
+	m = press(m, "d")
+	if m.appMode != ModeConfirm || !f.has("alpha") {
+		t.Fatalf("d killed without confirmation: mode %v, sessions %v", m.appMode, f.sessions)
+	}
+	m = press(m, "n")
+	if !f.has("alpha") {
+		t.Fatal("cancelled kill removed the session")
+	}
+
+	m = press(m, "d", "y")
+	if f.has("alpha") || !f.has("beta") {
+		t.Fatalf("expected only alpha killed, sessions %v", f.sessions)
+	}
+	if m.appMode != ModeNormal {
+		t.Errorf("mode after kill = %v, want normal", m.appMode)
+	}
+}
+
+func TestKillWithoutConfirmationAndUndo(t *testing.T) {
+	f := newFakeTmux("alpha", "beta")
+	m := newTestModel(t, f)
+	m.config.ConfirmKill = false
+
+	next, _ := m.Update(keyMsg("d"))
+	m = next.(model)
+	if f.has("alpha") {
+		t.Fatal("d didn't kill alpha")
+	}
+
+	next, cmd := m.Update(keyMsg("u"))
+	m = next.(model)
+	if cmd == nil {
+		t.Fatal("u returned no restore command")
+	}
+	next, _ = m.Update(cmd())
+	m = next.(model)
+	if !f.has("alpha") {
+		t.Fatalf("undo didn't recreate alpha: %s", m.message)
+	}
+}
+
+func TestBulkKillSelectedSessions(t *testing.T) {
+	f := newFakeTmux("alpha", "beta", "gamma")
+	m := newTestModel(t, f)
+
+	m = press(m, " ", " ", "d", "y")
+	if f.has("alpha") || f.has("beta") || !f.has("gamma") {
+		t.Fatalf("expected alpha and beta killed, sessions %v", f.sessions)
+	}
+}
+
+func TestRenameSession(t *testing.T) {
+	f := newFakeTmux("alpha", "beta")
+	m := newTestModel(t, f)
+	f.current = "alpha"
+
+	m = press(m, "r")
+	if m.appMode != ModeRename {
+		t.Fatalf("r didn't start renaming, mode %v", m.appMode)
+	}
+	for range "alpha" {
+		m = press(m, "backspace")
+	}
+	m = typeText(m, "work")
+	m = press(m, "enter")
+
+	if f.has("alpha") || !f.has("work") {
+		t.Fatalf("expected alpha renamed to work, sessions %v", f.sessions)
+	}
+	if f.current != "work" {
+		t.Errorf("current = %q, want work", f.current)
+	}
+}
+
+func TestNewNamedSession(t *testing.T) {
+	f := newFakeTmux("alpha")
+	m := newTestModel(t, f)
+
+	m = press(m, "n")
+	if m.appMode != ModeNewSession {
+		t.Fatalf("n didn't open new session mode, mode %v", m.appMode)
+	}
+	m = typeText(m, "scratch")
+	m = press(m, "enter")
+	if m.action != "create_named" || m.choice != "scratch" {
+		t.Fatalf("action %q choice %q, want create_named scratch", m.action, m.choice)
+	}
+
+	if err := m.runChoice(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.has("scratch") || f.current != "scratch" {
+		t.Fatalf("expected to be in new session scratch, current %q sessions %v", f.current, f.sessions)
+	}
+}
+
+func TestEnterSwitchesToSelectedSession(t *testing.T) {
+	f := newFakeTmux("alpha", "beta")
+	m := newTestModel(t, f)
+
+	m = press(m, "j", "enter")
+	if m.action != "switch" || m.choice != "beta" {
+		t.Fatalf("action %q choice %q, want switch beta", m.action, m.choice)
+	}
+
+	if err := m.runChoice(); err != nil {
+		t.Fatal(err)
+	}
+	if f.current != "beta" {
+		t.Errorf("current = %q, want beta", f.current)
+	}
+}
+
+func TestNewSessionPromptKeepsLongCloneRequests(t *testing.T) {
+	m := newTestModel(t, newFakeTmux("alpha"))
+
+	request := "gh:owner/repo --depth 1 --branch v2.0 --sparse src,docs"
+	m = typeText(press(m, "n"), request)
+	if got := m.searchInput.Value(); got != request {
+		t.Errorf("prompt = %q, want %q", got, request)
+	}
+}
+
+func TestSwitchOutsideTmuxAttaches(t *testing.T) {
+	t.Setenv("TMUX", "")
+	os.Unsetenv("TMUX")
+	f := newFakeTmux("alpha", "beta")
+
+	if err := switchTmuxSession(f, "beta"); err != nil {
+		t.Fatal(err)
+	}
+	if f.current != "beta" {
+		t.Errorf("current = %q, want beta", f.current)
+	}
+}
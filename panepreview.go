@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// The pane preview shows the last panePreviewLines lines of the selected
+// session's active pane, captured again every panePreviewInterval.
+const (
+	panePreviewLines    = 18
+	panePreviewWidth    = 56
+	panePreviewInterval = time.Second
+)
+
+type paneCaptureMsg struct {
+	session string
+	content string
+}
+
+// panePreviewTickMsg carries the generation of the tick loop that sent it, so
+// a loop left over from before the preview was last toggled stops.
+type panePreviewTickMsg struct {
+	generation int
+}
+
+func capturePane(t Tmux, session string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := t.CapturePane("=" + session + ":")
+		if err != nil {
+			content = ""
+		}
+		return paneCaptureMsg{session: session, content: content}
+	}
+}
+
+func panePreviewTick(generation int) tea.Cmd {
+	return tea.Tick(panePreviewInterval, func(time.Time) tea.Msg {
+		return panePreviewTickMsg{generation: generation}
+	})
+}
+
+// panePreviewContent keeps the last lines of captured pane content that fit
+// the detail panel, cut to its width with escape sequences intact.
+func panePreviewContent(content string) []string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(ansi.Strip(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > panePreviewLines {
+		lines = lines[len(lines)-panePreviewLines:]
+	}
+
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(strings.ReplaceAll(line, "\t", "    "), panePreviewWidth, "") + "\x1b[0m"
+	}
+	return lines
+}
+
+func buildPanePreview(session item, lines []string) string {
+	content := sessionSummary(session)
+	content = append(content, windowHeaderStyle.Render("▣ Active Pane"))
+	if lines == nil {
+		content = append(content, windowStyle.Render(inactiveIndicatorStyle.Render("Loading...")))
+	} else {
+		content = append(content, lines...)
+	}
+	return detailPanelStyle.Render(strings.Join(content, "\n"))
+}
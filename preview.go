@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Limits on what the project preview reads and shows.
+const (
+	previewWidth         = 52
+	previewReadmeLines   = 8
+	previewEntries       = 10
+	previewLanguageFiles = 2000
+)
+
+// projectPreview is what the New Session view shows about the highlighted
+// project.
+type projectPreview struct {
+	readme     []string
+	entries    []string
+	moreCount  int
+	language   string
+	git        gitStatus
+	hasSession bool
+}
+
+type projectPreviewMsg struct {
+	path    string
+	preview projectPreview
+}
+
+var languageExtensions = map[string]string{
+	".go":     "Go",
+	".rs":     "Rust",
+	".py":     "Python",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".swift":  "Swift",
+	".lua":    "Lua",
+	".sh":     "Shell",
+	".zig":    "Zig",
+	".php":    "PHP",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".hs":     "Haskell",
+	".scala":  "Scala",
+	".dart":   "Dart",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".nix":    "Nix",
+}
+
+// sessionTarget is the name of the session choosing the item creates or
+// switches to.
+func (i item) sessionTarget() string {
+	if i.isSession {
+		return i.title
+	}
+	if i.sessionName != "" {
+		return i.sessionName
+	}
+	return sessionNameForPath(i.path)
+}
+
+func loadProjectPreview(t Tmux, selected item, ignore []string) tea.Cmd {
+	return func() tea.Msg {
+		path := selected.path
+		preview := projectPreview{
+			readme:     readmeExcerpt(path),
+			language:   detectLanguage(path, ignore),
+			git:        readGitStatus(path),
+			hasSession: sessionExists(t, selected.sessionTarget()),
+		}
+		preview.entries, preview.moreCount = listEntries(path)
+		return projectPreviewMsg{path: path, preview: preview}
+	}
+}
+
+// readmeExcerpt returns the first non-blank lines of the project's README.
+func readmeExcerpt(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(name, "readme") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil
+		}
+
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "<") || strings.HasPrefix(line, "[![") {
+				continue
+			}
+			lines = append(lines, line)
+			if len(lines) == previewReadmeLines {
+				break
+			}
+		}
+		return lines
+	}
+	return nil
+}
+
+// listEntries lists the top of the project directory, directories first,
+// and how many entries didn't fit.
+func listEntries(dir string) ([]string, int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].IsDir() && !entries[j].IsDir()
+	})
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+
+	if len(names) > previewEntries {
+		return names[:previewEntries], len(names) - previewEntries
+	}
+	return names, 0
+}
+
+// detectLanguage names the language most of the project's source files are
+// written in, looking at a bounded number of files and skipping ignored
+// directories.
+func detectLanguage(dir string, ignore []string) string {
+	counts := map[string]int{}
+	seen := 0
+
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && ignored(entry.Name(), path, ignore) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		seen++
+		if seen > previewLanguageFiles {
+			return filepath.SkipAll
+		}
+		if language, ok := languageExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+			counts[language]++
+		}
+		return nil
+	})
+
+	best := ""
+	for language, count := range counts {
+		if count > counts[best] || (count == counts[best] && language < best) {
+			best = language
+		}
+	}
+	return best
+}
+
+func buildProjectPreview(selected item, preview *projectPreview) string {
+	header := detailHeaderStyle.Render(truncateToWidth(" "+selected.title, previewWidth))
+	content := []string{header, ""}
+
+	if preview == nil {
+		content = append(content, inactiveIndicatorStyle.Render("Loading..."))
+		return detailPanelStyle.Render(strings.Join(content, "\n"))
+	}
+
+	if preview.hasSession {
+		content = append(content, activeIndicatorStyle.Render("● Session "+selected.sessionTarget()+" exists"))
+	} else {
+		content = append(content, inactiveIndicatorStyle.Render("○ No session yet"))
+	}
+
+	language := preview.language
+	if language == "" {
+		language = selected.kind
+	}
+	if language != "" {
+		content = append(content, detailTextStyle.Render("Language: ")+projectKindStyle.Render(language))
+	}
+
+	if preview.git.ok {
+		content = append(content, preview.git.render())
+		if preview.git.subject != "" {
+			content = append(content, pathStyle.Render(truncateToWidth(preview.git.subject, previewWidth)))
+		}
+	}
+
+	readme, entries, moreCount := fitPreview(preview.readme, preview.entries, preview.moreCount, previewHeight()-len(content))
+
+	if len(readme) > 0 {
+		content = append(content, "", windowHeaderStyle.Render("README"))
+		for _, line := range readme {
+			content = append(content, detailTextStyle.Render(truncateToWidth(line, previewWidth)))
+		}
+	}
+
+	if len(entries) > 0 {
+		content = append(content, "", windowHeaderStyle.Render("Files"))
+		for _, name := range entries {
+			if strings.HasSuffix(name, "/") {
+				content = append(content, fileTreeStyle.Render(truncateToWidth(name, previewWidth)))
+			} else {
+				content = append(content, detailTextStyle.Render(truncateToWidth(name, previewWidth)))
+			}
+		}
+		if moreCount > 0 {
+			content = append(content, pathStyle.Render(fmt.Sprintf("… %d more", moreCount)))
+		}
+	}
+
+	return detailPanelStyle.Render(strings.Join(content, "\n"))
+}
+
+// previewHeight is how many lines fit inside the preview panel, which is as
+// tall as the list panel beside it.
+func previewHeight() int {
+	return detailPanelStyle.GetHeight() - detailPanelStyle.GetVerticalPadding()
+}
+
+// fitPreview shortens the README excerpt, and then the file list, so both
+// sections fit in room lines together with their headers. Entries that are
+// cut are added to the "more" count.
+func fitPreview(readme, entries []string, moreCount, room int) ([]string, []string, int) {
+	filesLines := 0
+	if len(entries) > 0 {
+		filesLines = 2 + len(entries)
+		if moreCount > 0 {
+			filesLines++
+		}
+	}
+
+	readmeRoom := room - filesLines - 2
+	if readmeRoom <= 0 {
+		readme = nil
+	} else if len(readme) > readmeRoom {
+		readme = readme[:readmeRoom]
+	}
+
+	left := room
+	if len(readme) > 0 {
+		left -= 2 + len(readme)
+	}
+	if filesLines > left {
+		shown := max(left-3, 0)
+		moreCount += len(entries) - shown
+		entries = entries[:shown]
+	}
+	return readme, entries, moreCount
+}
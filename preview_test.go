@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestProjectPreviewFitsListPanel(t *testing.T) {
+	preview := &projectPreview{
+		language:   "Go",
+		git:        gitStatus{ok: true, branch: "main", subject: "Fix the build"},
+		hasSession: true,
+		moreCount:  5,
+	}
+	for i := 0; i < previewReadmeLines; i++ {
+		preview.readme = append(preview.readme, fmt.Sprintf("readme line %d", i))
+	}
+	for i := 0; i < previewEntries; i++ {
+		preview.entries = append(preview.entries, fmt.Sprintf("file%d.go", i))
+	}
+
+	rendered := buildProjectPreview(item{title: "app", path: "/src/app"}, preview)
+	list := sessionListStyle.Render("")
+	if got, want := lipgloss.Height(rendered), lipgloss.Height(list); got != want {
+		t.Errorf("preview is %d lines tall, list panel %d", got, want)
+	}
+}
+
+func TestFitPreview(t *testing.T) {
+	lines := func(n int) []string {
+		return make([]string, n)
+	}
+
+	tests := []struct {
+		readme, entries, more, room int
+		wantReadme, wantEntries     int
+		wantMore                    int
+	}{
+		// Everything fits: 2+8 README lines and 2+10+1 file lines.
+		{8, 10, 5, 23, 8, 10, 5},
+		// The README gives way first.
+		{8, 10, 5, 20, 5, 10, 5},
+		// Then the file list, counting the cut entries as more.
+		{8, 10, 0, 11, 0, 8, 2},
+		{8, 10, 2, 8, 0, 5, 7},
+		{0, 3, 0, 5, 0, 3, 0},
+	}
+	for _, test := range tests {
+		readme, entries, more := fitPreview(lines(test.readme), lines(test.entries), test.more, test.room)
+		if len(readme) != test.wantReadme || len(entries) != test.wantEntries || more != test.wantMore {
+			t.Errorf("fitPreview(%d readme, %d entries, %d more, room %d) = %d, %d, %d; want %d, %d, %d",
+				test.readme, test.entries, test.more, test.room,
+				len(readme), len(entries), more, test.wantReadme, test.wantEntries, test.wantMore)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RemoteURL is a parsed git remote. CloneURL is what gets passed to git; for
+// shorthands it is the expanded template.
+type RemoteURL struct {
+	CloneURL string
+	Scheme   string
+	Host     string
+	Port     string
+	Owner    string
+	Repo     string
+}
+
+func DefaultRemoteShorthands() map[string]string {
+	return map[string]string{
+		"gh": "https://github.com/{path}",
+		"gl": "https://gitlab.com/{path}",
+		"bb": "https://bitbucket.org/{path}",
+	}
+}
+
+// scpLikePattern matches the scp-style syntax git accepts for ssh remotes,
+// e.g. git@github.com:owner/repo.git.
+var scpLikePattern = regexp.MustCompile(`^(?:([\w.-]+)@)?([\w.-]+):([^/].*)$`)
+
+func parseRemoteURL(input string, shorthands map[string]string) (RemoteURL, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return RemoteURL{}, fmt.Errorf("empty repository URL")
+	}
+
+	if prefix, path, ok := strings.Cut(input, ":"); ok && !strings.HasPrefix(path, "//") {
+		if template, found := shorthands[prefix]; found {
+			expanded := strings.ReplaceAll(template, "{path}", strings.Trim(path, "/"))
+			remote, err := parseRemoteURL(expanded, nil)
+			if err != nil {
+				return RemoteURL{}, fmt.Errorf("shorthand %s: %v", prefix, err)
+			}
+			return remote, nil
+		}
+	}
+
+	if strings.Contains(input, "://") {
+		u, err := url.Parse(input)
+		if err != nil {
+			return RemoteURL{}, fmt.Errorf("invalid repository URL: %v", err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh", "ssh+git":
+		default:
+			return RemoteURL{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+		if u.Hostname() == "" {
+			return RemoteURL{}, fmt.Errorf("repository URL has no host")
+		}
+		return newRemoteURL(input, u.Scheme, u.Hostname(), u.Port(), u.Path)
+	}
+
+	if match := scpLikePattern.FindStringSubmatch(input); match != nil {
+		host := match[2]
+		// Without a user, require a dotted host so "dir:file" style input
+		// isn't mistaken for a remote.
+		if match[1] == "" && !strings.Contains(host, ".") {
+			return RemoteURL{}, fmt.Errorf("not a repository URL: %s", input)
+		}
+		return newRemoteURL(input, "ssh", host, "", match[3])
+	}
+
+	return RemoteURL{}, fmt.Errorf("not a repository URL: %s", input)
+}
+
+func newRemoteURL(cloneURL, scheme, host, port, path string) (RemoteURL, error) {
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[len(parts)-1] == "" {
+		return RemoteURL{}, fmt.Errorf("repository URL needs an owner and a repository: %s", cloneURL)
+	}
+	// The host and path segments become directories under repos_path.
+	for _, segment := range append([]string{host}, parts...) {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `\`) {
+			return RemoteURL{}, fmt.Errorf("invalid path segment %q in repository URL: %s", segment, cloneURL)
+		}
+	}
+
+	return RemoteURL{
+		CloneURL: cloneURL,
+		Scheme:   scheme,
+		Host:     strings.ToLower(host),
+		Port:     port,
+		Owner:    strings.Join(parts[:len(parts)-1], "/"),
+		Repo:     parts[len(parts)-1],
+	}, nil
+}
+
+func isRepoURL(input string, config Config) bool {
+	_, err := parseRemoteURL(input, config.RemoteShorthands)
+	return err == nil
+}
+
+func extractRepoName(input string, config Config) string {
+	remote, err := parseRemoteURL(input, config.RemoteShorthands)
+	if err != nil {
+		return ""
+	}
+	return remote.Repo
+}
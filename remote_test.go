@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestParseRemoteURL(t *testing.T) {
+	shorthands := DefaultRemoteShorthands()
+	shorthands["work"] = "git@git.company.com:{path}.git"
+
+	tests := []struct {
+		input string
+		want  RemoteURL
+	}{
+		{"https://github.com/user/repo", RemoteURL{CloneURL: "https://github.com/user/repo", Scheme: "https", Host: "github.com", Owner: "user", Repo: "repo"}},
+		{"https://GitHub.com/user/repo.git/", RemoteURL{CloneURL: "https://GitHub.com/user/repo.git/", Scheme: "https", Host: "github.com", Owner: "user", Repo: "repo"}},
+		{"git@github.com:user/repo.git", RemoteURL{CloneURL: "git@github.com:user/repo.git", Scheme: "ssh", Host: "github.com", Owner: "user", Repo: "repo"}},
+		{"ssh://git@git.example.com:2222/team/repo.git", RemoteURL{CloneURL: "ssh://git@git.example.com:2222/team/repo.git", Scheme: "ssh", Host: "git.example.com", Port: "2222", Owner: "team", Repo: "repo"}},
+		{"git://gitea.example.com/user/repo", RemoteURL{CloneURL: "git://gitea.example.com/user/repo", Scheme: "git", Host: "gitea.example.com", Owner: "user", Repo: "repo"}},
+		{"git@gitlab.com:group/subgroup/repo.git", RemoteURL{CloneURL: "git@gitlab.com:group/subgroup/repo.git", Scheme: "ssh", Host: "gitlab.com", Owner: "group/subgroup", Repo: "repo"}},
+		{"https://gitlab.com/group/sub/deeper/repo", RemoteURL{CloneURL: "https://gitlab.com/group/sub/deeper/repo", Scheme: "https", Host: "gitlab.com", Owner: "group/sub/deeper", Repo: "repo"}},
+		{"gh:user/repo", RemoteURL{CloneURL: "https://github.com/user/repo", Scheme: "https", Host: "github.com", Owner: "user", Repo: "repo"}},
+		{"gl:group/subgroup/repo", RemoteURL{CloneURL: "https://gitlab.com/group/subgroup/repo", Scheme: "https", Host: "gitlab.com", Owner: "group/subgroup", Repo: "repo"}},
+		{"work:team/repo", RemoteURL{CloneURL: "git@git.company.com:team/repo.git", Scheme: "ssh", Host: "git.company.com", Owner: "team", Repo: "repo"}},
+		{"  https://github.com/user/repo  ", RemoteURL{CloneURL: "https://github.com/user/repo", Scheme: "https", Host: "github.com", Owner: "user", Repo: "repo"}},
+	}
+	for _, test := range tests {
+		got, err := parseRemoteURL(test.input, shorthands)
+		if err != nil {
+			t.Errorf("parseRemoteURL(%q) error: %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseRemoteURL(%q) = %+v, want %+v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseRemoteURLRejects(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"my-project",
+		"dir:file",
+		"C:/x",
+		`C:\x`,
+		"/home/me/repo",
+		"./repo",
+		"https://github.com/repo",
+		"https:///user/repo",
+		"ftp://example.com/user/repo",
+		"file:///srv/git/user/repo",
+		"https://github.com/../repo",
+		"https://github.com/user/..",
+		"https://github.com/./repo",
+		"git@github.com:user/../../repo.git",
+		"gh:repo",
+		"gh:../etc",
+	} {
+		if got, err := parseRemoteURL(input, DefaultRemoteShorthands()); err == nil {
+			t.Errorf("parseRemoteURL(%q) = %+v, want an error", input, got)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sessions can be selected in the session list so an action applies to all
+// of them at once. Every bulk action asks for confirmation first.
+
+// confirmListLimit is how many affected sessions the confirmation dialog
+// lists by name.
+const confirmListLimit = 18
+
+type bulkAction int
+
+const (
+	bulkKill bulkAction = iota
+	bulkDetach
+	bulkSnapshot
+)
+
+func (a bulkAction) verb() string {
+	switch a {
+	case bulkDetach:
+		return "Detach"
+	case bulkSnapshot:
+		return "Snapshot"
+	}
+	return "Kill"
+}
+
+func (a bulkAction) done() string {
+	switch a {
+	case bulkDetach:
+		return "detached"
+	case bulkSnapshot:
+		return "saved"
+	}
+	return "killed"
+}
+
+// toggleSelected selects or deselects the session the item belongs to.
+func (m *model) toggleSelected(it item) {
+	name := it.sessionTarget()
+	if m.selected[name] {
+		delete(m.selected, name)
+	} else {
+		m.selected[name] = true
+	}
+}
+
+// selectAll selects the sessions of the listed items, or clears the
+// selection when they are all selected already.
+func (m *model) selectAll(items []item) {
+	var names []string
+	allSelected := true
+	for _, it := range items {
+		name := it.sessionTarget()
+		names = append(names, name)
+		allSelected = allSelected && m.selected[name]
+	}
+
+	for _, name := range names {
+		if allSelected {
+			delete(m.selected, name)
+		} else {
+			m.selected[name] = true
+		}
+	}
+}
+
+// selectedSessions returns the selected sessions that still exist, in list
+// order.
+func (m model) selectedSessions() []string {
+	var names []string
+	for _, session := range m.sessions {
+		if m.selected[session.title] {
+			names = append(names, session.title)
+		}
+	}
+	return names
+}
+
+// actionTargets is the sessions a bulk action applies to: the selection, or
+// the highlighted session when nothing is selected.
+func (m model) actionTargets() []string {
+	if names := m.selectedSessions(); len(names) > 0 {
+		return names
+	}
+	if name := m.selectedSessionName(); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+func (m model) confirmBulkAction(action bulkAction) (tea.Model, tea.Cmd) {
+	targets := m.actionTargets()
+	if action == bulkDetach {
+		// Sessions without clients have nothing to detach.
+		var attached []string
+		for _, name := range targets {
+			if session, ok := m.sessionItem(name); ok && session.isAttached {
+				attached = append(attached, name)
+			}
+		}
+		if len(attached) == 0 {
+			m.message = "No attached sessions to detach"
+			return m, nil
+		}
+		targets = attached
+	}
+	if len(targets) == 0 {
+		return m, nil
+	}
+	m.appMode = ModeConfirm
+	m.confirmAction = action
+	m.confirmSessions = targets
+	return m, nil
+}
+
+func (m model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		targets := m.confirmSessions
+		m.appMode = ModeNormal
+		m.confirmSessions = nil
+		m.selected = map[string]bool{}
+		if m.confirmAction == bulkKill {
+			return m, m.killSessions(targets)
+		}
+		m.message = runBulkAction(m.tmux, m.confirmAction, targets)
+		return m, m.refreshItems()
+	case "n", "N", "esc", "q", "ctrl+c":
+		m.message = "Cancelled"
+	default:
+		return m, nil
+	}
+
+	m.appMode = ModeNormal
+	m.confirmSessions = nil
+	return m, m.refreshItems()
+}
+
+// runBulkAction detaches or snapshots the sessions and summarises the
+// result, carrying on past failures and reporting them together. Kills go
+// through killSessions so they can be undone.
+func runBulkAction(t Tmux, action bulkAction, sessions []string) string {
+	if action == bulkSnapshot {
+		path, err := snapshotSessions(t, sessions)
+		if err != nil {
+			return fmt.Sprintf("Error saving snapshot: %v", err)
+		}
+		return fmt.Sprintf("Saved %d session(s) as %s", len(sessions), strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+
+	var failed []string
+	for _, name := range sessions {
+		if err := t.Detach(name); err != nil {
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Sprintf("%d session(s) %s; failed: %s", len(sessions)-len(failed), action.done(), strings.Join(failed, ", "))
+	}
+	return fmt.Sprintf("%d session(s) %s", len(sessions), action.done())
+}
+
+// snapshotSessions saves a snapshot holding only the named sessions.
+func snapshotSessions(t Tmux, names []string) (string, error) {
+	snapshot, err := takeSessionsSnapshot(t, names)
+	if err != nil {
+		return "", err
+	}
+	return saveSnapshot(snapshot, "")
+}
+
+func takeSessionsSnapshot(t Tmux, names []string) (Snapshot, error) {
+	snapshot, err := takeSnapshot(t)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var sessions []SessionSnapshot
+	for _, session := range snapshot.Sessions {
+		if wanted[session.Name] {
+			sessions = append(sessions, session)
+		}
+	}
+	snapshot.Sessions = sessions
+	return snapshot, nil
+}
+
+func (m model) buildConfirmDialog() string {
+	header := detailHeaderStyle.Render(fmt.Sprintf(" %s %d session(s)?", m.confirmAction.verb(), len(m.confirmSessions)))
+	content := []string{header, ""}
+
+	for i, name := range m.confirmSessions {
+		if i == confirmListLimit {
+			content = append(content, pathStyle.Render(fmt.Sprintf("… %d more", len(m.confirmSessions)-i)))
+			break
+		}
+		line := detailTextStyle.Render("• " + truncateToWidth(name, listTitleWidth))
+		if session, ok := m.sessionItem(name); ok {
+			line += pathStyle.Render(fmt.Sprintf(" (%s windows)", session.windowCount))
+			if session.isAttached {
+				line += " " + activeIndicatorStyle.Render("⚡")
+			}
+		}
+		content = append(content, line)
+	}
+
+	return detailPanelStyle.Render(strings.Join(content, "\n"))
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot records every session of the tmux server so it can be recreated
+// after the server dies: windows in order with their layouts, and each pane's
+// working directory and the program running in it.
+type Snapshot struct {
+	Created  time.Time         `json:"created"`
+	Sessions []SessionSnapshot `json:"sessions"`
+}
+
+type SessionSnapshot struct {
+	Name    string           `json:"name"`
+	Windows []WindowSnapshot `json:"windows"`
+}
+
+type WindowSnapshot struct {
+	Name   string         `json:"name"`
+	Layout string         `json:"layout,omitempty"`
+	Active bool           `json:"active,omitempty"`
+	Panes  []PaneSnapshot `json:"panes"`
+}
+
+type PaneSnapshot struct {
+	Dir     string `json:"dir"`
+	Command string `json:"command,omitempty"`
+	Active  bool   `json:"active,omitempty"`
+}
+
+// savedSnapshot is a snapshot file in snapshotDir.
+type savedSnapshot struct {
+	name     string
+	path     string
+	snapshot Snapshot
+}
+
+const snapshotTimeFormat = "2006-01-02T15-04-05"
+
+// autoSnapshotPrefix marks the snapshots taken automatically. Only these are
+// rotated; snapshots saved by hand are kept until deleted.
+const autoSnapshotPrefix = "auto-"
+
+type AutoSnapshotConfig struct {
+	Interval string `json:"interval"`
+	Keep     int    `json:"keep"`
+}
+
+func DefaultAutoSnapshotConfig() AutoSnapshotConfig {
+	return AutoSnapshotConfig{
+		Interval: "15m",
+		Keep:     10,
+	}
+}
+
+// DefaultRestoreCommands are the programs restore starts again in their
+// panes. Anything else, like a build that was running, is left for the user
+// to rerun.
+func DefaultRestoreCommands() []string {
+	return []string{"vi", "vim", "nvim", "emacs", "nano", "hx", "man", "less", "more", "tail", "top", "htop", "btop", "lazygit"}
+}
+
+func snapshotDir() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "snapshots")
+}
+
+func isShell(command string) bool {
+	switch command {
+	case "", "bash", "zsh", "fish", "sh", "dash", "ksh", "tcsh", "nu":
+		return true
+	}
+	return false
+}
+
+func takeSnapshot(t Tmux) (Snapshot, error) {
+	panes, err := t.ListPanes()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to list panes: %v", err)
+	}
+
+	processes := listProcesses()
+
+	snapshot := Snapshot{Created: time.Now()}
+	var session *SessionSnapshot
+	var window *WindowSnapshot
+	var windowIndex string
+	for _, pane := range panes {
+		if session == nil || session.Name != pane.Session {
+			snapshot.Sessions = append(snapshot.Sessions, SessionSnapshot{Name: pane.Session})
+			session = &snapshot.Sessions[len(snapshot.Sessions)-1]
+			window = nil
+		}
+		if window == nil || windowIndex != pane.WindowIndex {
+			session.Windows = append(session.Windows, WindowSnapshot{
+				Name:   pane.WindowName,
+				Layout: pane.WindowLayout,
+				Active: pane.WindowActive,
+			})
+			window = &session.Windows[len(session.Windows)-1]
+			windowIndex = pane.WindowIndex
+		}
+
+		command := ""
+		if !isShell(pane.Command) {
+			command = processes.commandLine(pane)
+		}
+		window.Panes = append(window.Panes, PaneSnapshot{
+			Dir:     pane.Path,
+			Command: command,
+			Active:  pane.Active,
+		})
+	}
+	return snapshot, nil
+}
+
+// processTable maps process ids to their command lines and to the ids of
+// their children.
+type processTable struct {
+	args     map[int]string
+	children map[int][]int
+}
+
+// listProcesses reads the process table with ps, which pane_current_command
+// only names the program of.
+func listProcesses() processTable {
+	table := processTable{args: map[int]string{}, children: map[int][]int{}}
+
+	output, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,args=").Output()
+	if err != nil {
+		return table
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		table.args[pid] = strings.Join(fields[2:], " ")
+		table.children[ppid] = append(table.children[ppid], pid)
+	}
+	return table
+}
+
+// commandLine is the full command running in pane: the foreground child of
+// the pane's shell, or the pane's own process when it runs a program
+// directly. ps joins arguments with spaces and loses their quoting, so the
+// arguments are read from /proc and quoted again for the shell; where that
+// isn't possible only the program name is kept.
+func (p processTable) commandLine(pane Pane) string {
+	pid := 0
+	if children := p.children[pane.PID]; len(children) > 0 {
+		pid = children[0]
+	} else if args, ok := p.args[pane.PID]; ok && !isShell(filepath.Base(strings.Fields(args)[0])) {
+		pid = pane.PID
+	}
+	if pid == 0 {
+		return pane.Command
+	}
+
+	argv, err := processArgs(pid)
+	if err != nil || len(argv) == 0 {
+		return pane.Command
+	}
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// processArgs reads the arguments of a process, NUL-separated in
+// /proc/<pid>/cmdline.
+func processArgs(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("process %d has no command line", pid)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"), nil
+}
+
+// shellQuote quotes arg for a POSIX shell unless it only holds characters
+// that need no quoting.
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// saveSnapshot writes snapshot to snapshotDir under name, or under its
+// creation time when name is empty, and returns the file's path.
+func saveSnapshot(snapshot Snapshot, name string) (string, error) {
+	if name == "" {
+		name = snapshot.Created.Format(snapshotTimeFormat)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+
+	if err := os.MkdirAll(snapshotDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(snapshotDir(), name+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	return path, nil
+}
+
+func loadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// listSnapshots returns the saved snapshots, newest first. Unreadable files
+// are skipped.
+func listSnapshots() ([]savedSnapshot, error) {
+	entries, err := os.ReadDir(snapshotDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshots []savedSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(snapshotDir(), entry.Name())
+		snapshot, err := loadSnapshot(path)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, savedSnapshot{
+			name:     strings.TrimSuffix(entry.Name(), ".json"),
+			path:     path,
+			snapshot: snapshot,
+		})
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].snapshot.Created.After(snapshots[j].snapshot.Created)
+	})
+	return snapshots, nil
+}
+
+// findSnapshot returns the snapshot saved under name, or the newest one when
+// name is empty.
+func findSnapshot(name string) (savedSnapshot, error) {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return savedSnapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return savedSnapshot{}, fmt.Errorf("no snapshots saved in %s", snapshotDir())
+	}
+	if name == "" {
+		return snapshots[0], nil
+	}
+	for _, saved := range snapshots {
+		if saved.name == name {
+			return saved, nil
+		}
+	}
+	return savedSnapshot{}, fmt.Errorf("snapshot '%s' not found", name)
+}
+
+// saveAutoSnapshot takes a snapshot, saves it unless nothing changed since the
+// last automatic one, and removes automatic snapshots beyond the configured
+// number. It returns the saved file's path, or "" when nothing was saved. A
+// server that isn't running or has no sessions isn't saved, so closing the
+// last session doesn't rotate out the snapshots that still hold it.
+//
+// Hooks can run several of these at once, so the check, save and rotation
+// happen under a lock.
+func saveAutoSnapshot(t Tmux, config Config) (string, error) {
+	snapshot, err := takeSnapshot(t)
+	if err != nil {
+		if !tmuxServerRunning(t) {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(snapshot.Sessions) == 0 {
+		return "", nil
+	}
+
+	lock, err := lockSnapshotFile("rotate.lock", true)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Close()
+
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return "", err
+	}
+	var auto []savedSnapshot
+	for _, saved := range snapshots {
+		if strings.HasPrefix(saved.name, autoSnapshotPrefix) {
+			auto = append(auto, saved)
+		}
+	}
+	if len(auto) > 0 && len(diffSnapshots(auto[0].snapshot, snapshot)) == 0 {
+		return "", nil
+	}
+
+	path, err := saveSnapshot(snapshot, autoSnapshotPrefix+snapshot.Created.Format(snapshotTimeFormat))
+	if err != nil {
+		return "", err
+	}
+
+	keep := max(config.AutoSnapshot.Keep, 1)
+	if len(auto) >= keep {
+		for _, saved := range auto[keep-1:] {
+			if err := os.Remove(saved.path); err != nil && !os.IsNotExist(err) {
+				return path, fmt.Errorf("failed to remove old snapshot: %v", err)
+			}
+		}
+	}
+	return path, nil
+}
+
+// errLocked is returned by lockSnapshotFile when it doesn't wait and another
+// process holds the lock.
+var errLocked = errors.New("already locked by another process")
+
+// lockSnapshotFile takes an exclusive lock on the file name in snapshotDir,
+// waiting for it when wait is set and failing with errLocked otherwise.
+// Closing the file releases the lock.
+func lockSnapshotFile(name string, wait bool) (*os.File, error) {
+	if err := os.MkdirAll(snapshotDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(snapshotDir(), name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file, wait); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// restoreSnapshot recreates the sessions of snapshot that aren't running and
+// returns the names of those it restored and those it skipped because a
+// session with that name exists.
+func restoreSnapshot(t Tmux, config Config, snapshot Snapshot) ([]string, []string, error) {
+	var restored, skipped []string
+	for _, session := range snapshot.Sessions {
+		if t.HasSession(session.Name) {
+			skipped = append(skipped, session.Name)
+			continue
+		}
+		if err := restoreSession(t, config, session); err != nil {
+			return restored, skipped, fmt.Errorf("session '%s': %v", session.Name, err)
+		}
+		restored = append(restored, session.Name)
+	}
+	return restored, skipped, nil
+}
+
+func restoreSession(t Tmux, config Config, session SessionSnapshot) error {
+	if len(session.Windows) == 0 || len(session.Windows[0].Panes) == 0 {
+		return fmt.Errorf("snapshot has no windows")
+	}
+
+	var focusWindow string
+	for i, window := range session.Windows {
+		if len(window.Panes) == 0 {
+			continue
+		}
+
+		var windowTarget string
+		if i == 0 {
+			if err := t.NewSession(session.Name, window.Panes[0].Dir, false); err != nil {
+				return fmt.Errorf("failed to create session: %v", err)
+			}
+			target, err := t.ActivePane(session.Name + ":^")
+			if err != nil {
+				return fmt.Errorf("failed to find first window: %v", err)
+			}
+			windowTarget = target
+			if err := t.RenameWindow(windowTarget, window.Name); err != nil {
+				return fmt.Errorf("failed to rename window: %v", err)
+			}
+		} else {
+			target, err := t.NewWindow(session.Name, window.Name, window.Panes[0].Dir)
+			if err != nil {
+				return fmt.Errorf("failed to create window '%s': %v", window.Name, err)
+			}
+			windowTarget = target
+		}
+
+		paneTargets := []string{windowTarget}
+		for _, pane := range window.Panes[1:] {
+			target, err := t.SplitWindow(windowTarget, pane.Dir, false, "")
+			if err != nil {
+				return fmt.Errorf("failed to split window '%s': %v", window.Name, err)
+			}
+			paneTargets = append(paneTargets, target)
+			// Keep room for the next split; the saved layout is applied
+			// once all panes exist.
+			t.SelectLayout(windowTarget, "tiled")
+		}
+		if window.Layout != "" {
+			t.SelectLayout(windowTarget, window.Layout)
+		}
+
+		var focusPane string
+		for j, pane := range window.Panes {
+			if restoresCommand(config, pane.Command) {
+				t.SendKeys(paneTargets[j], pane.Command, "Enter")
+			}
+			if pane.Active {
+				focusPane = paneTargets[j]
+			}
+		}
+		if focusPane != "" {
+			t.SelectPane(focusPane)
+		}
+		if window.Active || focusWindow == "" {
+			focusWindow = windowTarget
+		}
+	}
+
+	if focusWindow != "" {
+		t.SelectWindow(focusWindow)
+	}
+	return nil
+}
+
+// restoresCommand reports whether restore_commands allows starting command
+// again; "*" allows everything.
+func restoresCommand(config Config, command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	program := filepath.Base(strings.Trim(fields[0], "'"))
+	for _, allowed := range config.RestoreCommands {
+		if allowed == "*" || allowed == program {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShellQuoteRoundTrips(t *testing.T) {
+	argv := []string{
+		"nvim",
+		"-c",
+		"lua if pcall(require, 'telescope') then vim.cmd('Telescope find_files') end",
+		"",
+		"$HOME",
+		`a "b" \c`,
+		"plain/path.go",
+	}
+
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = shellQuote(arg)
+	}
+	script := `for a in "$@"; do printf '[%s]' "$a"; done`
+	output, err := exec.Command("sh", "-c", "set -- "+strings.Join(quoted, " ")+"; "+script).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want string
+	for _, arg := range argv {
+		want += "[" + arg + "]"
+	}
+	if string(output) != want {
+		t.Errorf("shell saw %s, want %s", output, want)
+	}
+}
+
+func TestShellQuoteLeavesPlainWords(t *testing.T) {
+	for _, arg := range []string{"nvim", "-c", "src/main.go", "key=value"} {
+		if got := shellQuote(arg); got != arg {
+			t.Errorf("shellQuote(%q) = %q", arg, got)
+		}
+	}
+}
+
+func TestRestoresCommandQuotedProgram(t *testing.T) {
+	config := Config{RestoreCommands: []string{"nvim"}}
+	for command, want := range map[string]bool{
+		"nvim -c 'lua print(1)'":     true,
+		"'/usr/local/bin/nvim' file": true,
+		"make build":                 false,
+		"":                           false,
+	} {
+		if got := restoresCommand(config, command); got != want {
+			t.Errorf("restoresCommand(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestProcessArgsKeepsArguments(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Process.Kill()
+
+	if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+		t.Skip("no /proc:", err)
+	}
+	// Until the child has exec'd, it still shows the test binary's
+	// command line, or none at all.
+	var argv []string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if args, err := processArgs(cmd.Process.Pid); err == nil && args[0] == "sleep" {
+			argv = args
+			break
+		}
+	}
+	if want := []string{"sleep", "30"}; !reflect.DeepEqual(argv, want) {
+		t.Errorf("processArgs = %q, want %q", argv, want)
+	}
+}
+
+func TestSaveAutoSnapshotConcurrentRotation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for i := 0; i < 5; i++ {
+		old := Snapshot{
+			Created:  time.Date(2020, 1, 1, 0, 0, i, 0, time.UTC),
+			Sessions: []SessionSnapshot{{Name: fmt.Sprintf("old%d", i)}},
+		}
+		if _, err := saveSnapshot(old, autoSnapshotPrefix+old.Created.Format(snapshotTimeFormat)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := newFakeTmux("alpha", "beta")
+	config := Config{AutoSnapshot: AutoSnapshotConfig{Interval: "1m", Keep: 2}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := saveAutoSnapshot(f, config); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	snapshots, err := listSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("kept %d snapshots, want 2", len(snapshots))
+	}
+	if got := len(snapshots[0].snapshot.Sessions); got != 2 {
+		t.Errorf("newest snapshot has %d sessions, want 2", got)
+	}
+}
+
+func TestSaveAutoSnapshotWithoutServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	f := newFakeTmux()
+	f.down = true
+
+	path, err := saveAutoSnapshot(f, DefaultConfig())
+	if path != "" || err != nil {
+		t.Errorf("saveAutoSnapshot without a server = %q, %v; want nothing saved and no error", path, err)
+	}
+}
+
+func TestLockSnapshotFileHeld(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", "")
+
+	lock, err := lockSnapshotFile("test.lock", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockSnapshotFile("test.lock", false); !errors.Is(err, errLocked) {
+		t.Fatalf("second lock = %v, want errLocked", err)
+	}
+	lock.Close()
+
+	lock, err = lockSnapshotFile("test.lock", false)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	lock.Close()
+}
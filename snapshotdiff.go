@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffSnapshots describes how the sessions changed from before to after, one
+// change per line: "+" for added, "-" for removed and "~" for changed
+// sessions and windows. Windows are matched by name, and panes by position
+// within their window. Layouts and focus aren't compared, since they change
+// whenever a client resizes or moves around.
+func diffSnapshots(before, after Snapshot) []string {
+	var changes []string
+
+	oldSessions := map[string]SessionSnapshot{}
+	for _, session := range before.Sessions {
+		oldSessions[session.Name] = session
+	}
+	newSessions := map[string]bool{}
+	for _, session := range after.Sessions {
+		newSessions[session.Name] = true
+	}
+
+	for _, session := range before.Sessions {
+		if !newSessions[session.Name] {
+			changes = append(changes, fmt.Sprintf("- session %s (%d windows)", session.Name, len(session.Windows)))
+		}
+	}
+	for _, session := range after.Sessions {
+		previous, ok := oldSessions[session.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("+ session %s (%d windows)", session.Name, len(session.Windows)))
+			continue
+		}
+		for _, change := range diffWindows(previous.Windows, session.Windows) {
+			changes = append(changes, fmt.Sprintf("~ session %s: %s", session.Name, change))
+		}
+	}
+	return changes
+}
+
+// windowKeys names windows by their name and how many windows of the same
+// name come before them, so duplicate names still pair up in order.
+func windowKeys(windows []WindowSnapshot) []string {
+	seen := map[string]int{}
+	keys := make([]string, len(windows))
+	for i, window := range windows {
+		keys[i] = fmt.Sprintf("%s#%d", window.Name, seen[window.Name])
+		seen[window.Name]++
+	}
+	return keys
+}
+
+func diffWindows(before, after []WindowSnapshot) []string {
+	var changes []string
+
+	oldWindows := map[string]WindowSnapshot{}
+	for i, key := range windowKeys(before) {
+		oldWindows[key] = before[i]
+	}
+	newKeys := map[string]bool{}
+	for _, key := range windowKeys(after) {
+		newKeys[key] = true
+	}
+
+	for i, key := range windowKeys(before) {
+		if !newKeys[key] {
+			changes = append(changes, "- window "+before[i].Name)
+		}
+	}
+	for i, key := range windowKeys(after) {
+		window := after[i]
+		previous, ok := oldWindows[key]
+		if !ok {
+			changes = append(changes, "+ window "+window.Name)
+			continue
+		}
+		for _, change := range diffPanes(previous.Panes, window.Panes) {
+			changes = append(changes, fmt.Sprintf("window %s: %s", window.Name, change))
+		}
+	}
+	return changes
+}
+
+func diffPanes(before, after []PaneSnapshot) []string {
+	var changes []string
+	if len(before) != len(after) {
+		changes = append(changes, fmt.Sprintf("panes %d → %d", len(before), len(after)))
+	}
+	for i := 0; i < len(before) && i < len(after); i++ {
+		if before[i].Dir != after[i].Dir {
+			changes = append(changes, fmt.Sprintf("pane %d dir %s → %s", i, before[i].Dir, after[i].Dir))
+		}
+		if before[i].Command != after[i].Command {
+			changes = append(changes, fmt.Sprintf("pane %d command %s → %s", i, describeCommand(before[i].Command), describeCommand(after[i].Command)))
+		}
+	}
+	return changes
+}
+
+func describeCommand(command string) string {
+	if command == "" {
+		return "(shell)"
+	}
+	if strings.ContainsAny(command, " \t") {
+		return fmt.Sprintf("%q", command)
+	}
+	return command
+}
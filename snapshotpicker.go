@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type snapshotRestoredMsg struct {
+	restored []string
+	skipped  []string
+	err      error
+}
+
+func (msg snapshotRestoredMsg) summary() string {
+	if msg.err != nil {
+		return fmt.Sprintf("Error restoring snapshot: %v", msg.err)
+	}
+	var parts []string
+	if len(msg.restored) > 0 {
+		parts = append(parts, "Restored "+strings.Join(msg.restored, ", "))
+	}
+	if len(msg.skipped) > 0 {
+		parts = append(parts, "skipped running "+strings.Join(msg.skipped, ", "))
+	}
+	if len(parts) == 0 {
+		return "Snapshot has no sessions"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func restoreSnapshotCmd(t Tmux, config Config, snapshot Snapshot) tea.Cmd {
+	return func() tea.Msg {
+		restored, skipped, err := restoreSnapshot(t, config, snapshot)
+		return snapshotRestoredMsg{restored: restored, skipped: skipped, err: err}
+	}
+}
+
+// loadSnapshots lists the saved snapshots as the items of the snapshot
+// picker.
+func (m *model) loadSnapshots() {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		m.message = fmt.Sprintf("Error reading snapshots: %v", err)
+	}
+	m.snapshots = snapshots
+
+	var items []item
+	for _, saved := range snapshots {
+		items = append(items, item{
+			title: saved.name,
+			desc:  fmt.Sprintf("%d sessions", len(saved.snapshot.Sessions)),
+			path:  saved.path,
+		})
+	}
+	m.allItems = items
+	m.items = items
+	m.cursor = 0
+}
+
+func (m model) handleSnapshotMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.appMode = ModeNormal
+		m.cursor = 0
+		return m, m.refreshItems()
+
+	case "enter":
+		if m.cursor < len(m.snapshots) {
+			m.message = "Restoring " + m.snapshots[m.cursor].name + "..."
+			return m, restoreSnapshotCmd(m.tmux, m.config, m.snapshots[m.cursor].snapshot)
+		}
+
+	case "s":
+		snapshot, err := takeSnapshot(m.tmux)
+		if err == nil {
+			_, err = saveSnapshot(snapshot, "")
+		}
+		if err != nil {
+			m.message = fmt.Sprintf("Error saving snapshot: %v", err)
+			return m, nil
+		}
+		m.loadSnapshots()
+		m.message = fmt.Sprintf("Saved %d session(s)", len(snapshot.Sessions))
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	}
+	return m, nil
+}
+
+func buildSnapshotDetails(saved savedSnapshot) string {
+	header := detailHeaderStyle.Render(truncateToWidth(" "+saved.name, previewWidth))
+	content := []string{
+		header,
+		"",
+		detailTextStyle.Render("Saved: " + saved.snapshot.Created.Format("2006-01-02 15:04:05")),
+		"",
+	}
+
+	home := os.Getenv("HOME")
+	for _, session := range saved.snapshot.Sessions {
+		content = append(content, windowHeaderStyle.Render(truncateToWidth("⊞ "+session.Name, previewWidth)))
+		for _, window := range session.Windows {
+			content = append(content, windowStyle.Render(truncateToWidth(fmt.Sprintf("%s (%d panes)", window.Name, len(window.Panes)), previewWidth)))
+			for _, pane := range window.Panes {
+				line := strings.Replace(pane.Dir, home, "~", 1)
+				if pane.Command != "" {
+					line += " $ " + pane.Command
+				}
+				content = append(content, pathStyle.Render(truncateToWidth("    "+line, previewWidth)))
+			}
+		}
+	}
+	return detailPanelStyle.Render(strings.Join(content, "\n"))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rivo/uniseg"
+)
+
+// Display widths of names and paths in the lists, in terminal cells. Longer
+// text is cut at a grapheme boundary and ends in an ellipsis.
+const (
+	listTitleWidth = 30
+	listPathWidth  = 88
+	// compactPathWidth fits paths in the narrow project list shown next to
+	// a preview.
+	compactPathWidth = 40
+	ellipsis         = "…"
+)
+
+// grapheme is one user-perceived character: its text, the range of rune
+// indices it covers in the original string and its width in cells.
+type grapheme struct {
+	text      string
+	runeStart int
+	runeEnd   int
+	width     int
+}
+
+func splitGraphemes(text string) []grapheme {
+	var clusters []grapheme
+	runeIndex := 0
+
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		runeCount := len(g.Runes())
+		clusters = append(clusters, grapheme{
+			text:      g.Str(),
+			runeStart: runeIndex,
+			runeEnd:   runeIndex + runeCount,
+			width:     g.Width(),
+		})
+		runeIndex += runeCount
+	}
+	return clusters
+}
+
+func displayWidth(text string) int {
+	return uniseg.StringWidth(text)
+}
+
+// truncateToWidth shortens text to at most width cells without splitting a
+// grapheme, so combining accents and wide CJK characters stay intact.
+func truncateToWidth(text string, width int) string {
+	if width <= 0 || displayWidth(text) <= width {
+		return text
+	}
+
+	var result strings.Builder
+	used := 0
+	for _, cluster := range splitGraphemes(text) {
+		if used+cluster.width > width-displayWidth(ellipsis) {
+			break
+		}
+		result.WriteString(cluster.text)
+		used += cluster.width
+	}
+	return result.String() + ellipsis
+}
+
+// renderHighlights renders the graphemes of text containing a marked rune
+// index with highlightStyle, grouping neighbours into one styled run, and the
+// rest with base (or unstyled when base is nil). A grapheme is highlighted as
+// a whole so escape codes never land inside a cluster. Text wider than
+// maxWidth cells is truncated; maxWidth <= 0 means no limit.
+func renderHighlights(text string, marked map[int]bool, base *lipgloss.Style, maxWidth int) string {
+	clusters := splitGraphemes(text)
+
+	isMarked := func(cluster grapheme) bool {
+		for i := cluster.runeStart; i < cluster.runeEnd; i++ {
+			if marked[i] {
+				return true
+			}
+		}
+		return false
+	}
+
+	limit := 0
+	truncated := false
+	if maxWidth > 0 && displayWidth(text) > maxWidth {
+		limit = maxWidth - displayWidth(ellipsis)
+		truncated = true
+	}
+
+	var result strings.Builder
+	var run strings.Builder
+	runMarked := false
+	used := 0
+
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		chunk := run.String()
+		switch {
+		case runMarked:
+			result.WriteString(highlightStyle.Render(chunk))
+		case base != nil:
+			result.WriteString(base.Render(chunk))
+		default:
+			result.WriteString(chunk)
+		}
+		run.Reset()
+	}
+
+	for _, cluster := range clusters {
+		if truncated && used+cluster.width > limit {
+			break
+		}
+		if m := isMarked(cluster); m != runMarked {
+			flush()
+			runMarked = m
+		}
+		run.WriteString(cluster.text)
+		used += cluster.width
+	}
+	flush()
+
+	if truncated {
+		if base != nil {
+			result.WriteString(base.Render(ellipsis))
+		} else {
+			result.WriteString(ellipsis)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	family = "\U0001F468\u200D\U0001F469\u200D\U0001F467" // three emoji joined by ZWJ
+	eAcute = "e\u0301"                                    // e with a combining acute accent
+)
+
+func TestSplitGraphemes(t *testing.T) {
+	tests := []struct {
+		text string
+		want []grapheme
+	}{
+		{"ab", []grapheme{{"a", 0, 1, 1}, {"b", 1, 2, 1}}},
+		{"日本", []grapheme{{"日", 0, 1, 2}, {"本", 1, 2, 2}}},
+		{eAcute + "x", []grapheme{{eAcute, 0, 2, 1}, {"x", 2, 3, 1}}},
+		{family + "!", []grapheme{{family, 0, 5, 2}, {"!", 5, 6, 1}}},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := splitGraphemes(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitGraphemes(%q) = %+v, want %+v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello w…"},
+		{"hello", 0, "hello"},
+		// The cut falls inside the third wide character, which is dropped
+		// rather than split.
+		{"日本語テキスト", 6, "日本…"},
+		{"日本語テキスト", 5, "日本…"},
+		{"r" + eAcute + "sum" + eAcute + ".txt", 7, "r" + eAcute + "sum" + eAcute + "…"},
+		{family + " family", 3, family + "…"},
+		{family + " family", 2, "…"},
+	}
+	for _, test := range tests {
+		got := truncateToWidth(test.text, test.width)
+		if got != test.want {
+			t.Errorf("truncateToWidth(%q, %d) = %q, want %q", test.text, test.width, got, test.want)
+		}
+		if test.width > 0 && displayWidth(got) > test.width {
+			t.Errorf("truncateToWidth(%q, %d) is %d cells wide", test.text, test.width, displayWidth(got))
+		}
+	}
+}
+
+func TestRenderHighlights(t *testing.T) {
+	saved := highlightStyle
+	highlightStyle = lipgloss.NewStyle().Transform(func(s string) string { return "[" + s + "]" })
+	t.Cleanup(func() { highlightStyle = saved })
+
+	marks := func(indices ...int) map[int]bool {
+		marked := map[int]bool{}
+		for _, i := range indices {
+			marked[i] = true
+		}
+		return marked
+	}
+
+	tests := []struct {
+		text     string
+		marked   map[int]bool
+		maxWidth int
+		want     string
+	}{
+		{"mux-sesh", marks(0, 1, 4), 0, "[mu]x-[s]esh"},
+		{"日本語", marks(1), 0, "日[本]語"},
+		// Marking the combining mark highlights the whole grapheme.
+		{"caf" + eAcute, marks(4), 0, "caf[" + eAcute + "]"},
+		{family + "x", marks(2), 0, "[" + family + "]x"},
+		{"日本語テキスト", marks(0, 3), 6, "[日]本…"},
+		{"abcdef", marks(5), 4, "abc…"},
+	}
+	for _, test := range tests {
+		if got := renderHighlights(test.text, test.marked, nil, test.maxWidth); got != test.want {
+			t.Errorf("renderHighlights(%q, %v, %d) = %q, want %q", test.text, test.marked, test.maxWidth, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type Session struct {
+	Name     string
+	Attached bool
+	Windows  int
+}
+
+type Window struct {
+	Index   string
+	Name    string
+	Active  bool
+	Path    string
+	Command string
+}
+
+// Pane is one pane of a window, as listed across all sessions by ListPanes.
+type Pane struct {
+	Session      string
+	WindowIndex  string
+	WindowName   string
+	WindowActive bool
+	WindowLayout string
+	ID           string
+	Index        string
+	Active       bool
+	Path         string
+	Command      string
+	PID          int
+}
+
+// Tmux is every interaction mux-sesh has with the tmux server. The exec-backed
+// implementation shells out to the tmux binary; fakeTmux keeps state in memory.
+type Tmux interface {
+	ListSessions() ([]Session, error)
+	ListWindows(session string) ([]Window, error)
+	ListPanes() ([]Pane, error)
+	CapturePane(target string) (string, error)
+	NewSession(name, dir string, attach bool) error
+	SwitchClient(target string) error
+	Attach(target string) error
+	Kill(session string) error
+	Detach(session string) error
+	Rename(oldName, newName string) error
+	SendKeys(target string, keys ...string) error
+	HasSession(name string) bool
+	NewWindow(session, name, dir string) (string, error)
+	SplitWindow(target, dir string, horizontal bool, size string) (string, error)
+	RenameWindow(target, name string) error
+	SelectLayout(target, layout string) error
+	SelectWindow(target string) error
+	SelectPane(target string) error
+	ActivePane(target string) (string, error)
+}
+
+type execTmux struct{}
+
+// tmuxError replaces the exit status of a failed tmux command with what tmux
+// printed on stderr, which says why it failed.
+func tmuxError(args []string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if message := strings.TrimSpace(string(exitErr.Stderr)); message != "" {
+			return fmt.Errorf("tmux %s: %s", args[0], message)
+		}
+	}
+	return err
+}
+
+func (execTmux) run(args ...string) error {
+	_, err := exec.Command("tmux", args...).Output()
+	return tmuxError(args, err)
+}
+
+func (execTmux) output(args ...string) ([]string, error) {
+	output, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		return nil, tmuxError(args, err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func (t execTmux) ListSessions() ([]Session, error) {
+	lines, err := t.output("list-sessions", "-F", "#{session_name}\t#{session_attached}\t#{session_windows}")
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 {
+			continue
+		}
+		attached, _ := strconv.Atoi(parts[1])
+		windows, _ := strconv.Atoi(parts[2])
+		sessions = append(sessions, Session{
+			Name:     parts[0],
+			Attached: attached > 0,
+			Windows:  windows,
+		})
+	}
+	return sessions, nil
+}
+
+func (t execTmux) ListWindows(session string) ([]Window, error) {
+	lines, err := t.output("list-windows", "-t", "="+session, "-F", "#{window_index}\t#{window_name}\t#{window_active}\t#{pane_current_path}\t#{pane_current_command}")
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []Window
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 5 {
+			continue
+		}
+		windows = append(windows, Window{
+			Index:   parts[0],
+			Name:    parts[1],
+			Active:  parts[2] == "1",
+			Path:    parts[3],
+			Command: parts[4],
+		})
+	}
+	return windows, nil
+}
+
+func (t execTmux) ListPanes() ([]Pane, error) {
+	lines, err := t.output("list-panes", "-a", "-F", "#{session_name}\t#{window_index}\t#{window_name}\t#{window_active}\t#{window_layout}\t#{pane_id}\t#{pane_index}\t#{pane_active}\t#{pane_current_path}\t#{pane_current_command}\t#{pane_pid}")
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []Pane
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 11 {
+			continue
+		}
+		pid, _ := strconv.Atoi(parts[10])
+		panes = append(panes, Pane{
+			Session:      parts[0],
+			WindowIndex:  parts[1],
+			WindowName:   parts[2],
+			WindowActive: parts[3] == "1",
+			WindowLayout: parts[4],
+			ID:           parts[5],
+			Index:        parts[6],
+			Active:       parts[7] == "1",
+			Path:         parts[8],
+			Command:      parts[9],
+			PID:          pid,
+		})
+	}
+	return panes, nil
+}
+
+// CapturePane returns the visible contents of a pane, keeping the escape
+// sequences for colors and attributes.
+func (execTmux) CapturePane(target string) (string, error) {
+	args := []string{"capture-pane", "-p", "-e", "-t", target}
+	output, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		return "", tmuxError(args, err)
+	}
+	return string(output), nil
+}
+
+func (t execTmux) NewSession(name, dir string, attach bool) error {
+	args := []string{"new-session"}
+	if !attach {
+		args = append(args, "-d")
+	}
+	args = append(args, "-s", name)
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+
+	if !attach {
+		return t.run(args...)
+	}
+	cmd := exec.Command("tmux", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (t execTmux) SwitchClient(target string) error {
+	return t.run("switch-client", "-t", target)
+}
+
+// Attach attaches the terminal to the target, for when mux-sesh runs outside
+// tmux and there is no client to switch.
+func (execTmux) Attach(target string) error {
+	cmd := exec.Command("tmux", "attach-session", "-t", target)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (t execTmux) Kill(session string) error {
+	return t.run("kill-session", "-t", "="+session)
+}
+
+func (t execTmux) Detach(session string) error {
+	return t.run("detach-client", "-s", "="+session)
+}
+
+func (t execTmux) Rename(oldName, newName string) error {
+	return t.run("rename-session", "-t", "="+oldName, newName)
+}
+
+func (t execTmux) SendKeys(target string, keys ...string) error {
+	return t.run(append([]string{"send-keys", "-t", target}, keys...)...)
+}
+
+func (execTmux) HasSession(name string) bool {
+	return exec.Command("tmux", "has-session", "-t="+name).Run() == nil
+}
+
+func (t execTmux) NewWindow(session, name, dir string) (string, error) {
+	args := []string{"new-window", "-d", "-P", "-F", "#{pane_id}", "-t", "=" + session + ":"}
+	if name != "" {
+		args = append(args, "-n", name)
+	}
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+
+	lines, err := t.output(args...)
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("new-window failed: %v", err)
+	}
+	return lines[0], nil
+}
+
+func (t execTmux) SplitWindow(target, dir string, horizontal bool, size string) (string, error) {
+	args := []string{"split-window", "-d", "-P", "-F", "#{pane_id}", "-t", target}
+	if horizontal {
+		args = append(args, "-h")
+	} else {
+		args = append(args, "-v")
+	}
+	if size != "" {
+		args = append(args, "-l", size)
+	}
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+
+	lines, err := t.output(args...)
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("split-window failed: %v", err)
+	}
+	return lines[0], nil
+}
+
+func (t execTmux) RenameWindow(target, name string) error {
+	return t.run("rename-window", "-t", target, name)
+}
+
+func (t execTmux) SelectLayout(target, layout string) error {
+	return t.run("select-layout", "-t", target, layout)
+}
+
+func (t execTmux) SelectWindow(target string) error {
+	return t.run("select-window", "-t", target)
+}
+
+func (t execTmux) SelectPane(target string) error {
+	return t.run("select-pane", "-t", target)
+}
+
+func (t execTmux) ActivePane(target string) (string, error) {
+	lines, err := t.output("display-message", "-p", "-t", target, "#{pane_id}")
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("can't find pane: %s", target)
+	}
+	return lines[0], nil
+}
+
+func tmuxServerRunning(t Tmux) bool {
+	_, err := t.ListSessions()
+	return err == nil
+}
+
+// windowsBySession groups panes into the windows of each session, taking a
+// window's path and command from its active pane as list-windows does.
+func windowsBySession(panes []Pane) map[string][]Window {
+	windows := map[string][]Window{}
+	for _, pane := range panes {
+		list := windows[pane.Session]
+		if n := len(list); n == 0 || list[n-1].Index != pane.WindowIndex {
+			list = append(list, Window{
+				Index:  pane.WindowIndex,
+				Name:   pane.WindowName,
+				Active: pane.WindowActive,
+			})
+		}
+		if last := &list[len(list)-1]; pane.Active || last.Path == "" {
+			last.Path = pane.Path
+			last.Command = pane.Command
+		}
+		windows[pane.Session] = list
+	}
+	return windows
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type fakeWindow struct {
+	Window
+	panes  []string
+	layout string
+}
+
+type fakeSession struct {
+	dir     string
+	windows []*fakeWindow
+}
+
+// fakeTmux is an in-memory Tmux used to drive the model without a tmux server.
+// It records the client's current session and every send-keys call.
+type fakeTmux struct {
+	sessions map[string]*fakeSession
+	current  string
+	sentKeys map[string][]string
+	nextPane int
+	down     bool
+}
+
+func newFakeTmux(names ...string) *fakeTmux {
+	f := &fakeTmux{
+		sessions: map[string]*fakeSession{},
+		sentKeys: map[string][]string{},
+	}
+	for _, name := range names {
+		f.NewSession(name, "", false)
+	}
+	return f
+}
+
+func (f *fakeTmux) newPane() string {
+	f.nextPane++
+	return "%" + strconv.Itoa(f.nextPane)
+}
+
+// resolve understands the target forms mux-sesh uses: "session",
+// "session:^", "session:index" and "%pane".
+func (f *fakeTmux) resolve(target string) (string, *fakeWindow, error) {
+	target = strings.TrimPrefix(target, "=")
+
+	if strings.HasPrefix(target, "%") {
+		for name, session := range f.sessions {
+			for _, window := range session.windows {
+				for _, pane := range window.panes {
+					if pane == target {
+						return name, window, nil
+					}
+				}
+			}
+		}
+		return "", nil, fmt.Errorf("can't find pane: %s", target)
+	}
+
+	name, index, hasIndex := strings.Cut(target, ":")
+	session, ok := f.sessions[name]
+	if !ok {
+		return "", nil, fmt.Errorf("can't find session: %s", name)
+	}
+	if !hasIndex || index == "" {
+		for _, window := range session.windows {
+			if window.Active {
+				return name, window, nil
+			}
+		}
+		return name, session.windows[0], nil
+	}
+	if index == "^" {
+		return name, session.windows[0], nil
+	}
+	index, _, _ = strings.Cut(index, ".")
+	for _, window := range session.windows {
+		if window.Index == index {
+			return name, window, nil
+		}
+	}
+	return "", nil, fmt.Errorf("can't find window: %s", target)
+}
+
+func (f *fakeTmux) ListSessions() ([]Session, error) {
+	if f.down || len(f.sessions) == 0 {
+		return nil, fmt.Errorf("no server running")
+	}
+
+	var sessions []Session
+	for name, session := range f.sessions {
+		sessions = append(sessions, Session{
+			Name:     name,
+			Attached: name == f.current,
+			Windows:  len(session.windows),
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Name < sessions[j].Name
+	})
+	return sessions, nil
+}
+
+func (f *fakeTmux) ListWindows(session string) ([]Window, error) {
+	s, ok := f.sessions[session]
+	if !ok {
+		return nil, fmt.Errorf("can't find session: %s", session)
+	}
+
+	var windows []Window
+	for _, window := range s.windows {
+		windows = append(windows, window.Window)
+	}
+	return windows, nil
+}
+
+func (f *fakeTmux) ListPanes() ([]Pane, error) {
+	sessions, err := f.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []Pane
+	for _, session := range sessions {
+		for _, window := range f.sessions[session.Name].windows {
+			for i, id := range window.panes {
+				panes = append(panes, Pane{
+					Session:      session.Name,
+					WindowIndex:  window.Index,
+					WindowName:   window.Name,
+					WindowActive: window.Active,
+					WindowLayout: window.layout,
+					ID:           id,
+					Index:        strconv.Itoa(i),
+					Active:       i == 0,
+					Path:         window.Path,
+					Command:      window.Command,
+				})
+			}
+		}
+	}
+	return panes, nil
+}
+
+// CapturePane shows the keys sent to the pane's window, a line per target.
+func (f *fakeTmux) CapturePane(target string) (string, error) {
+	name, window, err := f.resolve(target)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for sentTo, keys := range f.sentKeys {
+		if sentName, sentWindow, err := f.resolve(sentTo); err == nil && sentName == name && sentWindow == window {
+			lines = append(lines, strings.Join(keys, " "))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func (f *fakeTmux) NewSession(name, dir string, attach bool) error {
+	if f.down {
+		return fmt.Errorf("no server running")
+	}
+	if _, ok := f.sessions[name]; ok {
+		return fmt.Errorf("duplicate session: %s", name)
+	}
+
+	f.sessions[name] = &fakeSession{
+		dir: dir,
+		windows: []*fakeWindow{{
+			Window: Window{
+				Index:   "1",
+				Name:    "shell",
+				Active:  true,
+				Path:    dir,
+				Command: "zsh",
+			},
+			panes: []string{f.newPane()},
+		}},
+	}
+	if attach {
+		f.current = name
+	}
+	return nil
+}
+
+// SwitchClient needs a client to switch, which exists only inside tmux.
+func (f *fakeTmux) SwitchClient(target string) error {
+	if _, insideTmux := os.LookupEnv("TMUX"); !insideTmux {
+		return fmt.Errorf("no current client")
+	}
+	return f.Attach(target)
+}
+
+func (f *fakeTmux) Attach(target string) error {
+	name, _, err := f.resolve(target)
+	if err != nil {
+		return err
+	}
+	f.current = name
+	return nil
+}
+
+func (f *fakeTmux) Kill(session string) error {
+	if _, ok := f.sessions[session]; !ok {
+		return fmt.Errorf("can't find session: %s", session)
+	}
+	delete(f.sessions, session)
+	if f.current == session {
+		f.current = ""
+	}
+	return nil
+}
+
+func (f *fakeTmux) Detach(session string) error {
+	if _, ok := f.sessions[session]; !ok {
+		return fmt.Errorf("can't find session: %s", session)
+	}
+	if f.current == session {
+		f.current = ""
+	}
+	return nil
+}
+
+func (f *fakeTmux) Rename(oldName, newName string) error {
+	s, ok := f.sessions[oldName]
+	if !ok {
+		return fmt.Errorf("can't find session: %s", oldName)
+	}
+	if _, ok := f.sessions[newName]; ok {
+		return fmt.Errorf("duplicate session: %s", newName)
+	}
+	delete(f.sessions, oldName)
+	f.sessions[newName] = s
+	if f.current == oldName {
+		f.current = newName
+	}
+	return nil
+}
+
+func (f *fakeTmux) SendKeys(target string, keys ...string) error {
+	if _, _, err := f.resolve(target); err != nil {
+		return err
+	}
+	f.sentKeys[target] = append(f.sentKeys[target], keys...)
+	return nil
+}
+
+func (f *fakeTmux) HasSession(name string) bool {
+	_, ok := f.sessions[name]
+	return ok
+}
+
+func (f *fakeTmux) NewWindow(session, name, dir string) (string, error) {
+	s, ok := f.sessions[strings.TrimSuffix(strings.TrimPrefix(session, "="), ":")]
+	if !ok {
+		return "", fmt.Errorf("can't find session: %s", session)
+	}
+
+	pane := f.newPane()
+	s.windows = append(s.windows, &fakeWindow{
+		Window: Window{
+			Index:   strconv.Itoa(len(s.windows) + 1),
+			Name:    name,
+			Path:    dir,
+			Command: "zsh",
+		},
+		panes: []string{pane},
+	})
+	return pane, nil
+}
+
+func (f *fakeTmux) SplitWindow(target, dir string, horizontal bool, size string) (string, error) {
+	_, window, err := f.resolve(target)
+	if err != nil {
+		return "", err
+	}
+
+	pane := f.newPane()
+	window.panes = append(window.panes, pane)
+	return pane, nil
+}
+
+func (f *fakeTmux) RenameWindow(target, name string) error {
+	_, window, err := f.resolve(target)
+	if err != nil {
+		return err
+	}
+	window.Name = name
+	return nil
+}
+
+func (f *fakeTmux) SelectLayout(target, layout string) error {
+	_, window, err := f.resolve(target)
+	if err != nil {
+		return err
+	}
+	window.layout = layout
+	return nil
+}
+
+func (f *fakeTmux) SelectWindow(target string) error {
+	name, window, err := f.resolve(target)
+	if err != nil {
+		return err
+	}
+	for _, w := range f.sessions[name].windows {
+		w.Active = w == window
+	}
+	return nil
+}
+
+func (f *fakeTmux) SelectPane(target string) error {
+	_, _, err := f.resolve(target)
+	return err
+}
+
+func (f *fakeTmux) ActivePane(target string) (string, error) {
+	_, window, err := f.resolve(target)
+	if err != nil {
+		return "", err
+	}
+	return window.panes[0], nil
+}
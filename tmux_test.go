@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestTmuxErrorIncludesStderr(t *testing.T) {
+	_, err := exec.Command("sh", "-c", "echo 'no current client' >&2; exit 1").Output()
+	if got := tmuxError([]string{"switch-client", "-t", "work"}, err); got.Error() != "tmux switch-client: no current client" {
+		t.Errorf("tmuxError = %q", got)
+	}
+
+	_, err = exec.Command("sh", "-c", "exit 1").Output()
+	if got := tmuxError([]string{"kill-session"}, err); got != err {
+		t.Errorf("tmuxError without stderr = %q, want the exit status", got)
+	}
+}
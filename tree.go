@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sessions in the list expand into their windows, and windows with more than
+// one pane into their panes. Window and pane items carry the tmux target they
+// switch to, session:window or session:window.pane.
+
+func windowTarget(session string, window Window) string {
+	return session + ":" + window.Index
+}
+
+func paneTarget(pane Pane) string {
+	return pane.Session + ":" + pane.WindowIndex + "." + pane.Index
+}
+
+func windowItem(session string, window Window) item {
+	target := windowTarget(session, window)
+	return item{
+		title:       target + " " + window.Name,
+		desc:        strings.Replace(window.Path, os.Getenv("HOME"), "~", 1),
+		path:        target,
+		isAttached:  window.Active,
+		sessionName: session,
+		target:      target,
+		isWindow:    true,
+	}
+}
+
+func paneItem(pane Pane) item {
+	target := paneTarget(pane)
+	return item{
+		title:       target + " " + pane.Command,
+		desc:        strings.Replace(pane.Path, os.Getenv("HOME"), "~", 1),
+		path:        target,
+		isAttached:  pane.Active,
+		sessionName: pane.Session,
+		target:      target,
+		isPane:      true,
+	}
+}
+
+func (m model) windowPanes(session, windowIndex string) []Pane {
+	var panes []Pane
+	for _, pane := range m.sessionPanes[session] {
+		if pane.WindowIndex == windowIndex {
+			panes = append(panes, pane)
+		}
+	}
+	return panes
+}
+
+// sessionList is the session items with the windows and panes of expanded
+// sessions and windows listed below them.
+func (m model) sessionList() []item {
+	if len(m.expanded) == 0 {
+		return m.sessions
+	}
+
+	var items []item
+	for _, session := range m.sessions {
+		items = append(items, session)
+		if !m.expanded[session.title] {
+			continue
+		}
+		for _, window := range m.sessionWindows[session.title] {
+			items = append(items, windowItem(session.title, window))
+			panes := m.windowPanes(session.title, window.Index)
+			if len(panes) < 2 || !m.expanded[windowTarget(session.title, window)] {
+				continue
+			}
+			for _, pane := range panes {
+				items = append(items, paneItem(pane))
+			}
+		}
+	}
+	return items
+}
+
+// searchableSessions is every session followed by every window of every
+// session, so a search can jump straight to a window.
+func (m model) searchableSessions() []item {
+	items := append([]item{}, m.sessions...)
+	for _, session := range m.sessions {
+		for _, window := range m.sessionWindows[session.title] {
+			items = append(items, windowItem(session.title, window))
+		}
+	}
+	return items
+}
+
+// toggleExpanded expands or collapses the selected session or window. On a
+// pane it collapses the window the pane belongs to.
+func (m *model) toggleExpanded(selected item) {
+	key := selected.title
+	switch {
+	case selected.isWindow:
+		key = selected.target
+		windowIndex := strings.TrimPrefix(selected.target, selected.sessionName+":")
+		if len(m.windowPanes(selected.sessionName, windowIndex)) < 2 {
+			m.message = "Window has a single pane"
+			return
+		}
+	case selected.isPane:
+		key, _, _ = strings.Cut(selected.target, ".")
+	}
+
+	if m.expanded[key] {
+		delete(m.expanded, key)
+	} else if selected.isSession && m.loadingDetails && len(m.sessionWindows[key]) == 0 {
+		m.message = "Loading windows..."
+		return
+	} else {
+		m.expanded[key] = true
+	}
+
+	m.allItems = m.sessionList()
+	m.items = m.allItems
+	m.cursor = 0
+	for i, it := range m.items {
+		if it.path == key {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// collapseParent collapses the session or window the selected item is
+// listed under, or the item itself when it is expanded.
+func (m *model) collapseParent(selected item) {
+	switch {
+	case selected.isPane:
+		m.toggleExpanded(selected)
+	case selected.isWindow && m.expanded[selected.target]:
+		m.toggleExpanded(selected)
+	case selected.isWindow:
+		m.toggleExpanded(item{title: selected.sessionName, isSession: true})
+	case selected.isSession && m.expanded[selected.title]:
+		m.toggleExpanded(selected)
+	}
+}
+
+func (m model) sessionItem(name string) (item, bool) {
+	for _, session := range m.sessions {
+		if session.title == name {
+			return session, true
+		}
+	}
+	return item{}, false
+}
+
+func (i item) treeLine(number int, query string) string {
+	indent := "  "
+	symbol := "⊞"
+	if i.isPane {
+		indent = "    "
+		symbol = "▣"
+	}
+	title := highlightMultiWordMatches(i.title, query, listTitleWidth)
+	line := fmt.Sprintf("%d %s%s %s", number, indent, symbol, title)
+	if i.desc != "" {
+		descWidth := listTitleWidth - displayWidth(truncateToWidth(i.title, listTitleWidth))
+		if descWidth < 10 {
+			descWidth = 10
+		}
+		line += " " + pathStyle.Render(truncateToWidth(i.desc, descWidth))
+	}
+	return line
+}
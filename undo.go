@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Killed sessions are snapshotted just before the kill and can be recreated
+// with u for undoTimeout afterwards.
+const undoTimeout = 15 * time.Second
+
+type killUndo struct {
+	snapshot   Snapshot
+	generation int
+}
+
+// undoExpiredMsg carries the generation of the kill it expires, so a later
+// kill's undo isn't dropped early.
+type undoExpiredMsg struct {
+	generation int
+}
+
+func undoExpired(generation int) tea.Cmd {
+	return tea.Tick(undoTimeout, func(time.Time) tea.Msg {
+		return undoExpiredMsg{generation: generation}
+	})
+}
+
+// killSessions kills the named sessions, keeping a snapshot of them to undo
+// the kill with.
+func (m *model) killSessions(names []string) tea.Cmd {
+	snapshot, snapshotErr := takeSessionsSnapshot(m.tmux, names)
+
+	var killed, failed []string
+	for _, name := range names {
+		if err := killTmuxSession(m.tmux, name); err != nil {
+			failed = append(failed, name)
+		} else {
+			killed = append(killed, name)
+		}
+	}
+
+	if len(killed) == 1 {
+		m.message = fmt.Sprintf("Session '%s' killed", killed[0])
+	} else {
+		m.message = fmt.Sprintf("%d session(s) killed", len(killed))
+	}
+	if len(failed) > 0 {
+		m.message += "; failed: " + strings.Join(failed, ", ")
+	}
+	if len(killed) == 0 || snapshotErr != nil || len(snapshot.Sessions) == 0 {
+		return m.refreshItems()
+	}
+
+	m.undoGeneration++
+	m.undo = &killUndo{snapshot: snapshot, generation: m.undoGeneration}
+	m.message += " (u to undo)"
+	return tea.Batch(m.refreshItems(), undoExpired(m.undoGeneration))
+}
+
+func (m *model) undoKill() tea.Cmd {
+	if m.undo == nil {
+		m.message = "Nothing to undo"
+		return nil
+	}
+	snapshot := m.undo.snapshot
+	m.undo = nil
+	m.message = "Restoring..."
+	return restoreSnapshotCmd(m.tmux, m.config, snapshot)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type worktree struct {
+	path     string
+	branch   string
+	head     string
+	bare     bool
+	detached bool
+}
+
+func isGitRepo(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil
+}
+
+// listWorktrees parses `git worktree list --porcelain`. The main worktree is
+// always listed first.
+func listWorktrees(repo string) ([]worktree, error) {
+	output, err := exec.Command("git", "-C", repo, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %v", err)
+	}
+
+	var worktrees []worktree
+	var current *worktree
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			worktrees = append(worktrees, worktree{path: value})
+			current = &worktrees[len(worktrees)-1]
+		case "HEAD":
+			if current != nil {
+				current.head = value
+			}
+		case "branch":
+			if current != nil {
+				current.branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		case "bare":
+			if current != nil {
+				current.bare = true
+			}
+		case "detached":
+			if current != nil {
+				current.detached = true
+			}
+		}
+	}
+	return worktrees, nil
+}
+
+func (w worktree) name() string {
+	if w.branch != "" {
+		return w.branch
+	}
+	if len(w.head) >= 7 {
+		return w.head[:7]
+	}
+	return w.head
+}
+
+func worktreeSessionName(repoName string, w worktree) string {
+	return sanitizeSessionName(repoName + "/" + w.name())
+}
+
+// worktreeItems lists the checked-out worktrees of the repository at path as
+// project items named repo/branch.
+func worktreeItems(path string) ([]item, error) {
+	worktrees, err := listWorktrees(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(worktrees) == 0 {
+		return nil, fmt.Errorf("no worktrees found")
+	}
+
+	repoName := filepath.Base(worktrees[0].path)
+
+	var items []item
+	for _, w := range worktrees {
+		if w.bare {
+			continue
+		}
+		name := worktreeSessionName(repoName, w)
+		items = append(items, item{
+			title:       name,
+			desc:        strings.Replace(w.path, os.Getenv("HOME"), "~", 1),
+			path:        w.path,
+			parent:      path,
+			sessionName: name,
+			isWorktree:  true,
+		})
+	}
+	return items, nil
+}
+
+// hasRemoteBranch reports whether a remote of repo has a branch named branch.
+func hasRemoteBranch(repo, branch string) bool {
+	output, err := exec.Command("git", "-C", repo, "for-each-ref", "--format=%(refname)", "refs/remotes/*/"+branch).Output()
+	return err == nil && strings.TrimSpace(string(output)) != ""
+}
+
+// addWorktree checks out branch in a new worktree next to the repository,
+// creating the branch from HEAD when it doesn't exist yet. It returns the new
+// worktree's path and the session name to use for it.
+func addWorktree(repo, branch string) (string, string, error) {
+	worktrees, err := listWorktrees(repo)
+	if err != nil {
+		return "", "", err
+	}
+	if len(worktrees) == 0 {
+		return "", "", fmt.Errorf("no worktrees found")
+	}
+
+	mainPath := worktrees[0].path
+	repoName := filepath.Base(mainPath)
+	target := filepath.Join(filepath.Dir(mainPath), repoName+"-worktrees", strings.ReplaceAll(branch, "/", "-"))
+
+	// An existing local branch is checked out as is. A branch that only
+	// exists on a remote is checked out by name too, so git creates it
+	// tracking the remote one; only new branches start from HEAD.
+	args := []string{"-C", repo, "worktree", "add"}
+	if exec.Command("git", "-C", repo, "show-ref", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil || hasRemoteBranch(repo, branch) {
+		args = append(args, target, branch)
+	} else {
+		args = append(args, "-b", branch, target)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("failed to add worktree: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return target, worktreeSessionName(repoName, worktree{branch: branch}), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(cmd.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestAddWorktreeTracksRemoteBranch(t *testing.T) {
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin")
+	git(t, root, "init", "-q", "-b", "main", origin)
+	git(t, origin, "commit", "-q", "--allow-empty", "-m", "initial")
+	git(t, origin, "checkout", "-q", "-b", "feature")
+	git(t, origin, "commit", "-q", "--allow-empty", "-m", "feature work")
+	git(t, origin, "checkout", "-q", "main")
+
+	clone := filepath.Join(root, "repo")
+	git(t, root, "clone", "-q", origin, clone)
+
+	path, session, err := addWorktree(clone, "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session != "repo/feature" {
+		t.Errorf("session = %q, want repo/feature", session)
+	}
+	if got := git(t, path, "log", "-1", "--format=%s"); got != "feature work" {
+		t.Errorf("worktree is at %q, want the remote branch's commit", got)
+	}
+	if got := git(t, path, "rev-parse", "--abbrev-ref", "feature@{upstream}"); got != "origin/feature" {
+		t.Errorf("upstream = %q, want origin/feature", got)
+	}
+}
+
+func TestAddWorktreeCreatesNewBranch(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	git(t, filepath.Dir(repo), "init", "-q", "-b", "main", repo)
+	git(t, repo, "commit", "-q", "--allow-empty", "-m", "initial")
+
+	path, _, err := addWorktree(repo, "topic/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "topic-new" {
+		t.Errorf("path = %q, want it named topic-new", path)
+	}
+	if got := git(t, path, "rev-parse", "--abbrev-ref", "HEAD"); got != "topic/new" {
+		t.Errorf("branch = %q, want topic/new", got)
+	}
+}